@@ -0,0 +1,81 @@
+package gerror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AbortWithFileError aborts with 404 for os.ErrNotExist, 403 for
+// os.ErrPermission and 500 for any other error.
+func AbortWithFileError(c *gin.Context, err error) {
+	code := 500
+	if errors.Is(err, os.ErrNotExist) {
+		code = 404
+	} else if errors.Is(err, os.ErrPermission) {
+		code = 403
+	}
+	AbortWithError(c, code, err)
+}
+
+// verbatimBody is the meta of the errors pushed by AbortWithBody.
+type verbatimBody struct {
+	body interface{}
+}
+
+// AbortWithBody aborts with a body the middleware writes verbatim instead of
+// the one of ResponseBodyFunc, unless the status code doesn't allow a body.
+// A []byte body must hold serialized JSON.
+func AbortWithBody(c *gin.Context, code int, body interface{}) {
+	if data, ok := body.([]byte); ok {
+		body = json.RawMessage(data)
+	}
+	AbortWithMeta(c, code, nil, "", verbatimBody{body: body})
+}
+
+// AbortWithRateLimit aborts with 429 and the Retry-After and
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers.
+// retryAfter is in seconds.
+func AbortWithRateLimit(c *gin.Context, retryAfter int, limit, remaining, reset int) {
+	err := NewHint(429, fmt.Sprintf("Rate limit exceeded, retry in %d seconds", retryAfter)).(GError).
+		WithHeader("Retry-After", strconv.Itoa(retryAfter)).
+		WithHeader("X-RateLimit-Limit", strconv.Itoa(limit)).
+		WithHeader("X-RateLimit-Remaining", strconv.Itoa(remaining)).
+		WithHeader("X-RateLimit-Reset", strconv.Itoa(reset))
+	AbortWithError(c, 429, err)
+}
+
+// AbortWithMethodNotAllowed aborts with 405 and the Allow header listing the
+// allowed methods, as required by HTTP.
+func AbortWithMethodNotAllowed(c *gin.Context, allowed []string) {
+	err := NewHint(405, fmt.Sprintf("Method %s is not allowed", c.Request.Method)).(GError).
+		WithHeader("Allow", strings.Join(allowed, ", "))
+	AbortWithError(c, 405, err)
+}
+
+// AbortWithPayloadTooLarge aborts with 413 and a hint stating the maximum
+// size of the request body, e.g. set with http.MaxBytesReader.
+func AbortWithPayloadTooLarge(c *gin.Context, maxBytes int64) {
+	AbortWithHint(c, 413, fmt.Sprintf("Request body is larger than the limit of %s", formatBytes(maxBytes)))
+}
+
+// formatBytes formats a size in the largest binary unit dividing it.
+func formatBytes(n int64) string {
+	for _, unit := range []struct {
+		size int64
+		name string
+	}{{1 << 30, "GB"}, {1 << 20, "MB"}, {1 << 10, "KB"}} {
+		if n >= unit.size && n%unit.size == 0 {
+			return fmt.Sprintf("%d %s", n/unit.size, unit.name)
+		}
+	}
+	if n == 1 {
+		return "1 byte"
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
@@ -0,0 +1,150 @@
+package gerror
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestAbortWithFileError(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	t.Run("not exist", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			_, err := os.Open("does-not-exist.txt")
+			AbortWithFileError(c, err)
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 404)
+		assert.Equal(t, "open does-not-exist.txt: no such file or directory", readLog(t))
+	})
+	t.Run("permission", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithFileError(c, fmt.Errorf("open secret.txt: %w", os.ErrPermission))
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 403)
+		assert.Equal(t, "open secret.txt: permission denied", readLog(t))
+	})
+	t.Run("generic error", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithFileError(c, errors.New("disk failure"))
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 500)
+		assert.Equal(t, "disk failure", readLog(t))
+	})
+}
+
+func TestAbortWithBody(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		IncludeCode: true,
+		ResponseBodyFunc: func(code int, message string) interface{} {
+			return gin.H{"unexpected": true}
+		},
+	}))
+	rawPath := getTestPath()
+	router.GET(rawPath, func(c *gin.Context) {
+		AbortWithBody(c, 502, []byte(`{"upstream":{"status":503,"error":"maintenance"}}`))
+	})
+	valuePath := getTestPath()
+	router.GET(valuePath, func(c *gin.Context) {
+		AbortWithBody(c, 409, gin.H{"conflict": "user 42"})
+	})
+
+	res := performRequest(router, "GET", rawPath)
+	assert.Equal(t, 502, res.Code)
+	assert.Equal(t, `{"upstream":{"status":503,"error":"maintenance"}}`, res.Body.String())
+	readLog(t)
+	res = performRequest(router, "GET", valuePath)
+	assert.Equal(t, 409, res.Code)
+	assert.Equal(t, `{"conflict":"user 42"}`, res.Body.String())
+	readLog(t)
+}
+
+func TestAbortWithBodyWithoutBodyStatus(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithBody(c, 304, gin.H{"cached": true})
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 304, res.Code)
+	assert.Empty(t, res.Body.String())
+	readLog(t)
+}
+
+func TestAbortWithBodyThenError(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithBody(c, 502, gin.H{"upstream": "maintenance"})
+		AbortWithHint(c, 409, "User already exists")
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 409, res.Code)
+	assert.Equal(t, `{"message":"User already exists"}`, res.Body.String())
+	readLog(t)
+}
+
+func TestAbortWithRateLimit(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{IncludeCode: true}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithRateLimit(c, 30, 100, 0, 1621512000)
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 429, res.Code)
+	assert.Equal(t, "30", res.Header().Get("Retry-After"))
+	assert.Equal(t, "100", res.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", res.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1621512000", res.Header().Get("X-RateLimit-Reset"))
+	assert.Equal(t, `{"code":429,"message":"Rate limit exceeded, retry in 30 seconds"}`, res.Body.String())
+	readLog(t)
+}
+
+func TestAbortWithMethodNotAllowed(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.DELETE(path, func(c *gin.Context) {
+		AbortWithMethodNotAllowed(c, []string{"GET", "HEAD", "PUT"})
+	})
+	res := performRequest(router, "DELETE", path)
+	assert.Equal(t, 405, res.Code)
+	assert.Equal(t, "GET, HEAD, PUT", res.Header().Get("Allow"))
+	assert.Equal(t, `{"message":"Method DELETE is not allowed"}`, res.Body.String())
+	readLog(t)
+}
+
+func TestAbortWithPayloadTooLarge(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{IncludeCode: true}))
+	path := getTestPath()
+	router.POST(path, func(c *gin.Context) {
+		AbortWithPayloadTooLarge(c, 10<<20)
+	})
+	res := performRequest(router, "POST", path)
+	assert.Equal(t, 413, res.Code)
+	assert.Equal(t, `{"code":413,"message":"Request body is larger than the limit of 10 MB"}`, res.Body.String())
+	readLog(t)
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "1 byte", formatBytes(1))
+	assert.Equal(t, "1500 bytes", formatBytes(1500))
+	assert.Equal(t, "512 KB", formatBytes(512<<10))
+	assert.Equal(t, "1536 KB", formatBytes(1536<<10))
+	assert.Equal(t, "10 MB", formatBytes(10<<20))
+	assert.Equal(t, "2 GB", formatBytes(2<<30))
+}
@@ -0,0 +1,40 @@
+package gerror
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// baggageValues reads the selected keys from the W3C baggage header propagated
+// by OpenTelemetry.
+func baggageValues(c *gin.Context, keys []string) map[string]string {
+	if len(keys) == 0 {
+		return nil
+	}
+	values := map[string]string{}
+	for _, header := range c.Request.Header.Values("baggage") {
+		for _, member := range strings.Split(header, ",") {
+			member = strings.SplitN(member, ";", 2)[0]
+			kv := strings.SplitN(member, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			key := strings.TrimSpace(kv[0])
+			value, err := url.PathUnescape(strings.TrimSpace(kv[1]))
+			if err != nil {
+				continue
+			}
+			for _, k := range keys {
+				if k == key {
+					values[key] = value
+				}
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
@@ -0,0 +1,31 @@
+package gerror
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBaggageKeys(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{BaggageKeys: []string{"tenant", "user"}}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 503, "unavailable")
+	})
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("baggage", "tenant=acme%20corp;ttl=60, user=42,secret=s3cr3t")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, res.Code, 503)
+	assert.Equal(t, `{"message":"unavailable","baggage":{"tenant":"acme corp","user":"42"}}`, res.Body.String())
+	readLog(t)
+	assert.Equal(t, "acme corp", hook.LastEntry().Data["tenant"])
+	assert.Equal(t, "42", hook.LastEntry().Data["user"])
+	assert.NotContains(t, hook.LastEntry().Data, "secret")
+}
@@ -0,0 +1,109 @@
+package gerror
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+const (
+	captureCallerKey = "github.com/dcalsky/gerror/captureCaller"
+	captureStackKey  = "github.com/dcalsky/gerror/captureStack"
+	modulePath       = "github.com/dcalsky/gerror"
+)
+
+// inPackage reports whether the frame belongs to this package or one of its
+// subpackages, like dberr, whose abort helpers are left out of callers and
+// stacks.
+func inPackage(frame runtime.Frame) bool {
+	pkg := framePackage(frame.Function)
+	return pkg == modulePath || strings.HasPrefix(pkg, modulePath+"/")
+}
+
+// callerName returns the name of the first function on the stack outside of
+// this package, i.e. the handler calling the abort helper.
+func callerName() string {
+	return firstFrame(inPackage)
+}
+
+// panicCaller returns the name of the function that panicked, when called
+// from a deferred function of this package.
+func panicCaller() string {
+	return firstFrame(func(frame runtime.Frame) bool {
+		return inPackage(frame) || framePackage(frame.Function) == "runtime"
+	})
+}
+
+func firstFrame(skip func(frame runtime.Frame) bool) string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !skip(frame) {
+			return frame.Function
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// funcName returns the name of a function value, like a handler passed to
+// Handle.
+func funcName(fn interface{}) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
+
+type stackOptions struct {
+	maxFrames    int
+	skipPackages []string
+}
+
+// stackTrace formats the stack from the caller of the abort helper like
+// debug.Stack, without the frames of skipPackages and with at most maxFrames
+// frames if it is positive.
+func stackTrace(options stackOptions) string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	var b strings.Builder
+	count, caller := 0, false
+	for {
+		frame, more := frames.Next()
+		caller = caller || !inPackage(frame)
+		if caller && !skipFrame(frame, options.skipPackages) {
+			if options.maxFrames > 0 && count == options.maxFrames {
+				break
+			}
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+			count++
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+func skipFrame(frame runtime.Frame, skipPackages []string) bool {
+	pkg := framePackage(frame.Function)
+	for _, skip := range skipPackages {
+		if pkg == skip || strings.HasPrefix(pkg, skip+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// framePackage returns the package path of a function name like
+// "github.com/gin-gonic/gin.(*Context).Next".
+func framePackage(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
@@ -0,0 +1,205 @@
+package gerror_test
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/dcalsky/gerror"
+	"github.com/dcalsky/gerror/dberr"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The tests live outside of the package, as its own frames are left out of
+// callers and stacks.
+
+func performRequest(r http.Handler, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// discardLogs discards the logs of the standard logger until the returned
+// function is called.
+func discardLogs() func() {
+	out := logrus.StandardLogger().Out
+	logrus.SetOutput(io.Discard)
+	return func() {
+		logrus.SetOutput(out)
+	}
+}
+
+func createUserHandler(c *gin.Context) {
+	gerror.AbortWithErrorAndHint(c, 409, errors.New("duplicate key"), "User already exists")
+}
+
+func TestCaptureCaller(t *testing.T) {
+	defer discardLogs()()
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	var callers []string
+	router := gin.New()
+	router.Use(gerror.Middleware(gerror.MiddlewareOption{
+		CaptureCaller: true,
+		AfterResponseFunc: func(c *gin.Context, gErr gerror.GError) {
+			callers = append(callers, gErr.Caller)
+		},
+	}))
+	path := "/users-1"
+	router.GET(path, createUserHandler)
+	closurePath := "/users-2"
+	router.GET(closurePath, func(c *gin.Context) {
+		gerror.AbortWithHint(c, 404, "User not found")
+	})
+
+	performRequest(router, path)
+	assert.Equal(t, "github.com/dcalsky/gerror_test.createUserHandler", hook.LastEntry().Data["caller"])
+	performRequest(router, closurePath)
+	assert.Equal(t, []string{
+		"github.com/dcalsky/gerror_test.createUserHandler",
+		"github.com/dcalsky/gerror_test.TestCaptureCaller.func2",
+	}, callers)
+}
+
+func getUser(c *gin.Context) (string, error) {
+	return "", gerror.NewHint(404, "User not found")
+}
+
+func deleteUserHandler(c *gin.Context) {
+	var users map[string]string
+	users[c.Param("id")] = ""
+}
+
+func TestCaptureCallerOutsideOfHandler(t *testing.T) {
+	defer discardLogs()()
+	var callers []string
+	router := gin.New()
+	router.Use(gerror.Middleware(gerror.MiddlewareOption{
+		CaptureCaller: true,
+		RecoverPanics: true,
+		AfterResponseFunc: func(c *gin.Context, gErr gerror.GError) {
+			callers = append(callers, gErr.Caller)
+		},
+	}))
+	router.GET("/users-9", func(c *gin.Context) {
+		dberr.AbortWithDBError(c, sql.ErrNoRows)
+	})
+	router.GET("/users-10", gerror.Handle(getUser))
+	router.GET("/users-11/:id", gerror.Recover(deleteUserHandler))
+	router.GET("/users-12/:id", deleteUserHandler)
+
+	for _, path := range []string{"/users-9", "/users-10", "/users-11/42", "/users-12/42"} {
+		performRequest(router, path)
+	}
+	assert.Equal(t, []string{
+		"github.com/dcalsky/gerror_test.TestCaptureCallerOutsideOfHandler.func2",
+		"github.com/dcalsky/gerror_test.getUser",
+		"github.com/dcalsky/gerror_test.deleteUserHandler",
+		"github.com/dcalsky/gerror_test.deleteUserHandler",
+	}, callers)
+}
+
+func TestCaptureCallerDisabled(t *testing.T) {
+	defer discardLogs()()
+	var caller string
+	router := gin.New()
+	router.Use(gerror.Middleware(gerror.MiddlewareOption{
+		AfterResponseFunc: func(c *gin.Context, gErr gerror.GError) {
+			caller = gErr.Caller
+		},
+	}))
+	path := "/users-3"
+	router.GET(path, createUserHandler)
+	performRequest(router, path)
+	assert.Empty(t, caller)
+}
+
+func TestCaptureStackSkipPackages(t *testing.T) {
+	defer discardLogs()()
+	var stack string
+	router := gin.New()
+	router.Use(gerror.Middleware(gerror.MiddlewareOption{
+		CaptureStack:      true,
+		StackSkipPackages: []string{"github.com/gin-gonic/gin", "net/http", "runtime", "testing"},
+		AfterResponseFunc: func(c *gin.Context, gErr gerror.GError) {
+			stack = gErr.StackTrace()
+		},
+	}))
+	path := "/users-4"
+	router.GET(path, createUserHandler)
+	performRequest(router, path)
+	assert.True(t, strings.HasPrefix(stack, "github.com/dcalsky/gerror_test.createUserHandler\n\t"))
+	assert.NotContains(t, stack, "github.com/gin-gonic/gin.")
+	assert.NotContains(t, stack, "runtime.")
+	assert.NotContains(t, stack, "gerror.AbortWithErrorAndHint")
+}
+
+func TestCaptureStackMaxFrames(t *testing.T) {
+	defer discardLogs()()
+	var stack string
+	router := gin.New()
+	router.Use(gerror.Middleware(gerror.MiddlewareOption{
+		CaptureStack:   true,
+		StackMaxFrames: 2,
+		AfterResponseFunc: func(c *gin.Context, gErr gerror.GError) {
+			stack = gErr.StackTrace()
+		},
+	}))
+	path := "/users-5"
+	router.GET(path, createUserHandler)
+	performRequest(router, path)
+	lines := strings.Split(strings.TrimSuffix(stack, "\n"), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, "github.com/dcalsky/gerror_test.createUserHandler", lines[0])
+	assert.Equal(t, "github.com/gin-gonic/gin.(*Context).Next", lines[2])
+}
+
+func TestCaptureStackDisabled(t *testing.T) {
+	defer discardLogs()()
+	var stack string
+	router := gin.New()
+	router.Use(gerror.Middleware(gerror.MiddlewareOption{
+		AfterResponseFunc: func(c *gin.Context, gErr gerror.GError) {
+			stack = gErr.StackTrace()
+		},
+	}))
+	path := "/users-6"
+	router.GET(path, createUserHandler)
+	performRequest(router, path)
+	assert.Empty(t, stack)
+}
+
+func TestIncludeHandlerName(t *testing.T) {
+	defer discardLogs()()
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	router := gin.New()
+	router.Use(gerror.Middleware(gerror.MiddlewareOption{IncludeHandlerName: true}))
+	path := "/users-7"
+	router.GET(path, func(c *gin.Context) {
+		c.Next()
+	}, createUserHandler)
+
+	performRequest(router, path)
+	assert.Equal(t, "github.com/dcalsky/gerror_test.createUserHandler", hook.LastEntry().Data["handler"])
+}
+
+func TestIncludeHandlerNameDisabled(t *testing.T) {
+	defer discardLogs()()
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	router := gin.New()
+	router.Use(gerror.Middleware(gerror.MiddlewareOption{}))
+	path := "/users-8"
+	router.GET(path, createUserHandler)
+
+	performRequest(router, path)
+	assert.NotContains(t, hook.LastEntry().Data, "handler")
+}
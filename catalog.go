@@ -0,0 +1,61 @@
+package gerror
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const paramsKey = "github.com/dcalsky/gerror/params"
+
+// AbortWithParams aborts without a hint, so the message is taken from the
+// catalog of MiddlewareOption.Messages with its {placeholders} replaced by params.
+func AbortWithParams(c *gin.Context, code int, params map[string]string) {
+	c.Set(paramsKey, params)
+	AbortWithHint(c, code, "")
+}
+
+func (option MiddlewareOption) catalogMessage(c *gin.Context, code int) string {
+	template, ok := "", false
+	for _, language := range acceptedLanguages(c.GetHeader("Accept-Language")) {
+		if template, ok = option.Messages[language][code]; ok {
+			break
+		}
+	}
+	if !ok {
+		template = option.Messages[option.DefaultLanguage][code]
+	}
+	params := c.GetStringMapString(paramsKey)
+	if len(params) == 0 {
+		return template
+	}
+	oldnew := make([]string, 0, len(params)*2)
+	for key, value := range params {
+		oldnew = append(oldnew, "{"+key+"}", value)
+	}
+	return strings.NewReplacer(oldnew...).Replace(template)
+}
+
+func acceptedLanguages(header string) []string {
+	var languages []string
+	for _, part := range strings.Split(header, ",") {
+		language := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if language == "" || language == "*" {
+			continue
+		}
+		languages = append(languages, language)
+		if i := strings.Index(language, "-"); i > 0 {
+			languages = append(languages, language[:i])
+		}
+	}
+	return languages
+}
+
+// defaultHint returns the message of the code from CodeCatalog or DefaultHints.
+func (option MiddlewareOption) defaultHint(code int) (string, bool) {
+	if entry := option.CodeCatalog[code]; entry.Message != "" {
+		return entry.Message, true
+	}
+	hint, ok := option.DefaultHints[code]
+	return hint, ok
+}
@@ -0,0 +1,107 @@
+package gerror
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func performRequestWithLanguage(r http.Handler, path, language string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("Accept-Language", language)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMessageCatalog(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		Messages: map[string]map[int]string{
+			"en": {
+				403: "Forbidden",
+				404: "User {id} not found",
+			},
+			"zh": {
+				404: "未找到用户 {id}",
+			},
+		},
+		DefaultLanguage: "en",
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithParams(c, 404, map[string]string{"id": "42"})
+	})
+	forbiddenPath := getTestPath()
+	router.GET(forbiddenPath, func(c *gin.Context) {
+		AbortWithHint(c, 403, "")
+	})
+	hintPath := getTestPath()
+	router.GET(hintPath, func(c *gin.Context) {
+		AbortWithHint(c, 404, "custom hint")
+	})
+
+	t.Run("english", func(t *testing.T) {
+		res := performRequestWithLanguage(router, path, "en-US,en;q=0.9")
+		assert.Equal(t, res.Code, 404)
+		body := parseBody(t, res)
+		assert.Equal(t, "User 42 not found", body["message"])
+	})
+	t.Run("chinese", func(t *testing.T) {
+		res := performRequestWithLanguage(router, path, "zh-CN,zh;q=0.9,en;q=0.8")
+		assert.Equal(t, res.Code, 404)
+		body := parseBody(t, res)
+		assert.Equal(t, "未找到用户 42", body["message"])
+	})
+	t.Run("default language", func(t *testing.T) {
+		res := performRequestWithLanguage(router, forbiddenPath, "zh-CN")
+		assert.Equal(t, res.Code, 403)
+		body := parseBody(t, res)
+		assert.Equal(t, "Forbidden", body["message"])
+	})
+	t.Run("hint first", func(t *testing.T) {
+		res := performRequestWithLanguage(router, hintPath, "zh-CN")
+		assert.Equal(t, res.Code, 404)
+		body := parseBody(t, res)
+		assert.Equal(t, "custom hint", body["message"])
+	})
+}
+
+func TestCodeCatalog(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		CodeCatalog: map[int]CodeEntry{
+			404: {Message: "Resource not found", Level: logrus.InfoLevel},
+			503: {Level: logrus.WarnLevel},
+		},
+		DefaultHints: map[int]string{
+			404: "Not here",
+			503: "Down for maintenance",
+		},
+	}))
+	notFoundPath := getTestPath()
+	router.GET(notFoundPath, func(c *gin.Context) {
+		AbortWithError(c, 404, errors.New("no rows"))
+	})
+	unavailablePath := getTestPath()
+	router.GET(unavailablePath, func(c *gin.Context) {
+		AbortWithError(c, 503, errors.New("migrating"))
+	})
+
+	res := performRequest(router, "GET", notFoundPath)
+	assert.Equal(t, `{"message":"Resource not found"}`, res.Body.String())
+	assert.Equal(t, "no rows", readLog(t))
+	assert.Equal(t, logrus.InfoLevel, hook.LastEntry().Level)
+
+	res = performRequest(router, "GET", unavailablePath)
+	assert.Equal(t, `{"message":"Down for maintenance"}`, res.Body.String())
+	assert.Equal(t, "migrating", readLog(t))
+	assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+}
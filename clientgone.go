@@ -0,0 +1,30 @@
+package gerror
+
+import (
+	"context"
+	"errors"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func isClientGone(err error) bool {
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, context.Canceled)
+}
+
+func clientGone(c *gin.Context, err error) bool {
+	return isClientGone(err) || errors.Is(c.Request.Context().Err(), context.Canceled)
+}
+
+// recoverClientGone recovers from the panic of gin's render when writing the
+// response fails because the client has disconnected.
+func recoverClientGone() {
+	if r := recover(); r != nil {
+		if err, ok := r.(error); ok && isClientGone(err) {
+			logrus.Debugf("gerror: client is gone: %v", err)
+			return
+		}
+		panic(r)
+	}
+}
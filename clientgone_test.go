@@ -0,0 +1,68 @@
+package gerror
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+)
+
+type brokenPipeWriter struct {
+	header http.Header
+	code   int
+}
+
+func (w *brokenPipeWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *brokenPipeWriter) Write([]byte) (int, error) {
+	return 0, &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}
+}
+
+func (w *brokenPipeWriter) WriteHeader(code int) {
+	w.code = code
+}
+
+func TestClientGone(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	t.Run("broken pipe", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithErrorAndHint(c, 500, errors.New("upstream error"), "upstream failed")
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req, _ := http.NewRequestWithContext(ctx, "GET", path, nil)
+		w := &brokenPipeWriter{header: http.Header{}}
+		assert.NotPanics(t, func() {
+			router.ServeHTTP(w, req)
+		})
+		assert.Equal(t, 500, w.code)
+		assert.Equal(t, "", readLog(t))
+	})
+	t.Run("canceled error", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithError(c, 500, context.Canceled)
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 500)
+		assert.Equal(t, "", readLog(t))
+	})
+	t.Run("server error", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithError(c, 500, errors.New("server error"))
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 500)
+		assert.Equal(t, "server error", readLog(t))
+	})
+}
@@ -0,0 +1,31 @@
+package gerror
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+const collectedErrorKey = "github.com/dcalsky/gerror/collectedError"
+
+// MiddlewareCollect resolves the error like Middleware, but only stores it in
+// the context instead of logging it and writing the response. An outer handler
+// can read it with CollectedError and decide what to do.
+func MiddlewareCollect(option MiddlewareOption) gin.HandlerFunc {
+	option = option.withDefaults()
+	return func(c *gin.Context) {
+		c.Set(middlewareKey, true)
+		c.Next()
+		if c.GetBool(skipKey) {
+			return
+		}
+		if gError, _, ok := option.resolveError(c); ok {
+			c.Set(collectedErrorKey, gError)
+		}
+	}
+}
+
+// CollectedError returns the error stored by MiddlewareCollect.
+func CollectedError(c *gin.Context) (GError, bool) {
+	value, _ := c.Get(collectedErrorKey)
+	gError, ok := value.(GError)
+	return gError, ok
+}
@@ -0,0 +1,42 @@
+package gerror
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMiddlewareCollect(t *testing.T) {
+	var collected GError
+	var found bool
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		collected, found = CollectedError(c)
+	})
+	router.Use(MiddlewareCollect(MiddlewareOption{}))
+	t.Run("aborted", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithErrorAndHint(c, 409, errors.New("collect error"), "conflict")
+		})
+		res := performRequest(router, "GET", path)
+		assert.True(t, found)
+		assert.Equal(t, 409, collected.Code)
+		assert.Equal(t, "conflict", collected.Hint)
+		assert.EqualError(t, collected.Err, "collect error")
+		assert.Equal(t, 200, res.Code)
+		assert.Equal(t, 0, res.Body.Len())
+		assert.Equal(t, "", readLog(t))
+	})
+	t.Run("not aborted", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			c.String(200, "ok")
+		})
+		res := performRequest(router, "GET", path)
+		assert.False(t, found)
+		assert.Equal(t, "ok", res.Body.String())
+	})
+}
@@ -0,0 +1,17 @@
+package gerror
+
+import (
+	"bytes"
+	"encoding/csv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeCSV writes the message as a single-column CSV with an "error" header,
+// so clients of export endpoints can still parse the response.
+func writeCSV(c *gin.Context, code int, message string) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.WriteAll([][]string{{"error"}, {message}})
+	c.Data(code, "text/csv; charset=utf-8", buf.Bytes())
+}
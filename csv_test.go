@@ -0,0 +1,20 @@
+package gerror
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCSVErrors(t *testing.T) {
+	router := gin.New()
+	export := router.Group("/export", Middleware(MiddlewareOption{CSVErrors: true}))
+	export.GET("/users.csv", func(c *gin.Context) {
+		AbortWithHint(c, 400, `Invalid range "2021-13", expected YYYY-MM`)
+	})
+	res := performRequest(router, "GET", "/export/users.csv")
+	assert.Equal(t, 400, res.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Equal(t, "error\n\"Invalid range \"\"2021-13\"\", expected YYYY-MM\"\n", res.Body.String())
+	readLog(t)
+}
@@ -0,0 +1,61 @@
+// Package dberr maps common database errors to status codes for gerror.
+//
+// It doesn't import any driver: PostgreSQL errors are recognized by their
+// SQLState method, which lib/pq and pgx implement, and MySQL errors by the
+// Number of go-sql-driver/mysql's MySQLError.
+package dberr
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+
+	"github.com/dcalsky/gerror"
+	"github.com/gin-gonic/gin"
+)
+
+// Code returns the status code of a database error: 404 for sql.ErrNoRows,
+// 409 for a unique violation, 503 for a deadlock, which can be retried, and
+// 500 for any other error.
+func Code(err error) int {
+	if errors.Is(err, sql.ErrNoRows) {
+		return 404
+	}
+	var pgError interface{ SQLState() string }
+	if errors.As(err, &pgError) {
+		switch pgError.SQLState() {
+		case "23505": // unique_violation
+			return 409
+		case "40P01": // deadlock_detected
+			return 503
+		}
+	}
+	if number, ok := mysqlErrorNumber(err); ok {
+		switch number {
+		case 1062: // ER_DUP_ENTRY
+			return 409
+		case 1213: // ER_LOCK_DEADLOCK
+			return 503
+		}
+	}
+	return 500
+}
+
+// AbortWithDBError aborts with the error and its status code from Code.
+func AbortWithDBError(c *gin.Context, err error) {
+	gerror.AbortWithError(c, Code(err), err)
+}
+
+// mysqlErrorNumber returns the Number of the first MySQLError in the chain.
+func mysqlErrorNumber(err error) (uint16, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.Indirect(reflect.ValueOf(err))
+		if v.Kind() != reflect.Struct || v.Type().Name() != "MySQLError" {
+			continue
+		}
+		if number := v.FieldByName("Number"); number.IsValid() && number.Kind() == reflect.Uint16 {
+			return uint16(number.Uint()), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,93 @@
+package dberr
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/dcalsky/gerror"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// pgError fakes the errors of lib/pq and pgx.
+type pgError struct {
+	code string
+}
+
+func (e *pgError) Error() string {
+	return "pq: error " + e.code
+}
+
+func (e *pgError) SQLState() string {
+	return e.code
+}
+
+// MySQLError fakes the errors of go-sql-driver/mysql.
+type MySQLError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *MySQLError) Error() string {
+	return fmt.Sprintf("Error %d: %s", e.Number, e.Message)
+}
+
+func performRequest(r http.Handler, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCode(t *testing.T) {
+	duplicateEntry := &MySQLError{Number: 1062, Message: "Duplicate entry 'a@b.c' for key 'email'"}
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{sql.ErrNoRows, 404},
+		{fmt.Errorf("find user: %w", sql.ErrNoRows), 404},
+		{&pgError{code: "23505"}, 409},
+		{fmt.Errorf("create user: %w", &pgError{code: "23505"}), 409},
+		{&pgError{code: "40P01"}, 503},
+		{&pgError{code: "23503"}, 500},
+		{duplicateEntry, 409},
+		{fmt.Errorf("create user: %w", &MySQLError{Number: 1213}), 503},
+		{&MySQLError{Number: 1045}, 500},
+		{errors.New("connection refused"), 500},
+	} {
+		assert.Equal(t, tc.code, Code(tc.err), tc.err.Error())
+	}
+}
+
+func TestAbortWithDBError(t *testing.T) {
+	var codes []int
+	router := gin.New()
+	router.Use(gerror.Middleware(gerror.MiddlewareOption{
+		IncludeCode: true,
+		DefaultHints: map[int]string{
+			404: "Not found",
+			409: "Already exists",
+		},
+		LoggingFunc: func(code int, err error) {
+			codes = append(codes, code)
+		},
+	}))
+	router.GET("/users/:id", func(c *gin.Context) {
+		AbortWithDBError(c, fmt.Errorf("find user: %w", sql.ErrNoRows))
+	})
+	router.GET("/signup", func(c *gin.Context) {
+		AbortWithDBError(c, &pgError{code: "23505"})
+	})
+
+	res := performRequest(router, "/users/42")
+	assert.Equal(t, 404, res.Code)
+	assert.Equal(t, `{"code":404,"message":"Not found"}`, res.Body.String())
+	res = performRequest(router, "/signup")
+	assert.Equal(t, 409, res.Code)
+	assert.Equal(t, `{"code":409,"message":"Already exists"}`, res.Body.String())
+	assert.Equal(t, []int{404, 409}, codes)
+}
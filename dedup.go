@@ -0,0 +1,42 @@
+package gerror
+
+import (
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+const keepDuplicatesKey = "github.com/dcalsky/gerror/keepDuplicates"
+
+// repeatsLastError reports whether the error is the same as the last error of
+// the context, pushed with the same meta, e.g. when aborting in a retry loop.
+// The caller and the stack are not compared.
+func repeatsLastError(c *gin.Context, gError GError, meta interface{}) bool {
+	lastError := c.Errors.Last()
+	if lastError == nil {
+		return false
+	}
+	last, ok := lastError.Err.(GError)
+	if !ok || last.Code != gError.Code || last.Hint != gError.Hint ||
+		last.Reason != gError.Reason || last.ReasonPhrase != gError.ReasonPhrase || last.Public != gError.Public {
+		return false
+	}
+	if !reflect.DeepEqual(last.Headers, gError.Headers) || !reflect.DeepEqual(last.Meta, gError.Meta) ||
+		!reflect.DeepEqual(last.Details, gError.Details) || !reflect.DeepEqual(last.Fields, gError.Fields) ||
+		!reflect.DeepEqual(last.Errors, gError.Errors) || !reflect.DeepEqual(lastError.Meta, meta) {
+		return false
+	}
+	return sameError(last.Err, gError.Err)
+}
+
+// sameError compares the errors with ==, which panics when they hold an
+// uncomparable value, e.g. a struct error wrapping a GError. Such errors are
+// never the same.
+func sameError(a, b error) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
@@ -0,0 +1,46 @@
+//go:build go1.19
+
+package gerror
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SendEarlyHints sends a 103 Early Hints response with the Link headers, like
+// `</style.css>; rel=preload; as=style`, before the handler writes the final
+// response or aborts. The hints are dropped once the response is written.
+func SendEarlyHints(c *gin.Context, links []string) {
+	if c.Writer.Written() || len(links) == 0 {
+		return
+	}
+	w, ok := underlyingWriter(c.Writer)
+	if !ok {
+		return
+	}
+	for _, link := range links {
+		c.Writer.Header().Add("Link", link)
+	}
+	// The status is written to the underlying writer, as gin would consider
+	// the response written and drop the final status.
+	w.WriteHeader(http.StatusEarlyHints)
+}
+
+// underlyingWriter returns the http.ResponseWriter wrapped by gin.
+func underlyingWriter(w gin.ResponseWriter) (http.ResponseWriter, bool) {
+	if unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter }); ok {
+		return unwrapper.Unwrap(), true
+	}
+	v := reflect.Indirect(reflect.ValueOf(w))
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+	field := v.FieldByName("ResponseWriter")
+	if !field.IsValid() || !field.CanInterface() {
+		return nil, false
+	}
+	underlying, ok := field.Interface().(http.ResponseWriter)
+	return underlying, ok && underlying != nil
+}
@@ -0,0 +1,84 @@
+//go:build go1.19
+
+package gerror
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"testing"
+)
+
+type earlyHints struct {
+	code  int
+	links []string
+}
+
+func getWithEarlyHints(t *testing.T, url string) (*http.Response, string, []earlyHints) {
+	var hints []earlyHints
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			hints = append(hints, earlyHints{code: code, links: header.Values("Link")})
+			return nil
+		},
+	}
+	req, _ := http.NewRequest("GET", url, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	res, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res, string(body), hints
+}
+
+func TestSendEarlyHints(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	router.GET("/page", func(c *gin.Context) {
+		SendEarlyHints(c, []string{"</style.css>; rel=preload; as=style", "</app.js>; rel=preload; as=script"})
+		c.String(200, "page")
+	})
+	router.GET("/missing", func(c *gin.Context) {
+		SendEarlyHints(c, []string{"</style.css>; rel=preload; as=style"})
+		AbortWithErrorAndHint(c, 404, errors.New("no rows"), "Page not found")
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	res, body, hints := getWithEarlyHints(t, server.URL+"/page")
+	assert.Equal(t, []earlyHints{{
+		code:  103,
+		links: []string{"</style.css>; rel=preload; as=style", "</app.js>; rel=preload; as=script"},
+	}}, hints)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "page", body)
+
+	res, body, hints = getWithEarlyHints(t, server.URL+"/missing")
+	readLog(t)
+	assert.Len(t, hints, 1)
+	assert.Equal(t, 404, res.StatusCode)
+	assert.Equal(t, `{"message":"Page not found"}`, body)
+}
+
+func TestSendEarlyHintsAfterWrite(t *testing.T) {
+	router := gin.New()
+	router.GET("/page", func(c *gin.Context) {
+		c.String(200, "page")
+		SendEarlyHints(c, []string{"</style.css>; rel=preload; as=style"})
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	res, body, hints := getWithEarlyHints(t, server.URL+"/page")
+	assert.Empty(t, hints)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "page", body)
+	assert.Empty(t, res.Header.Values("Link"))
+}
@@ -0,0 +1,27 @@
+package gerror
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func defaultErrorPage(code int) string {
+	return fmt.Sprintf("errors/%d.html", code)
+}
+
+// writeErrorPage writes the page of the status code from ErrorPageFS if the
+// client accepts HTML and the page exists.
+func writeErrorPage(c *gin.Context, fsys fs.FS, pageFunc func(code int) string, code int) bool {
+	if !strings.Contains(c.GetHeader("Accept"), "text/html") {
+		return false
+	}
+	page, err := fs.ReadFile(fsys, pageFunc(code))
+	if err != nil {
+		return false
+	}
+	c.Data(code, "text/html; charset=utf-8", page)
+	return true
+}
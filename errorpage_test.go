@@ -0,0 +1,54 @@
+package gerror
+
+import (
+	"embed"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+//go:embed testdata/errors
+var testErrorPages embed.FS
+
+func TestErrorPage(t *testing.T) {
+	pages, _ := fs.Sub(testErrorPages, "testdata")
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{ErrorPageFS: pages}))
+	notFoundPath := getTestPath()
+	router.GET(notFoundPath, func(c *gin.Context) {
+		AbortWithHint(c, 404, "not found")
+	})
+	conflictPath := getTestPath()
+	router.GET(conflictPath, func(c *gin.Context) {
+		AbortWithHint(c, 409, "conflict")
+	})
+	request := func(path, accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", accept)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		readLog(t)
+		return res
+	}
+
+	t.Run("html", func(t *testing.T) {
+		page, _ := fs.ReadFile(pages, "errors/404.html")
+		res := request(notFoundPath, "text/html,application/xhtml+xml")
+		assert.Equal(t, 404, res.Code)
+		assert.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+		assert.Equal(t, string(page), res.Body.String())
+	})
+	t.Run("json", func(t *testing.T) {
+		res := request(notFoundPath, "application/json")
+		assert.Equal(t, 404, res.Code)
+		assert.Equal(t, `{"message":"not found"}`, res.Body.String())
+	})
+	t.Run("missing page", func(t *testing.T) {
+		res := request(conflictPath, "text/html")
+		assert.Equal(t, 409, res.Code)
+		assert.Equal(t, `{"message":"conflict"}`, res.Body.String())
+	})
+}
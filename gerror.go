@@ -1,14 +1,46 @@
 package gerror
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"regexp"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
 type GError struct {
-	Code int    `json:"code"`
-	Err  error  `json:"err"`
-	Hint string `json:"hint"`
+	Code         int                    `json:"code"`
+	Err          error                  `json:"err"`
+	Hint         string                 `json:"hint"`
+	Errors       []error                `json:"errors"`
+	ReasonPhrase string                 `json:"reasonPhrase"`
+	Meta         map[string]interface{} `json:"meta"`
+	Details      []interface{}          `json:"details"`
+	Reason       string                 `json:"reason"`
+	Headers      http.Header            `json:"headers"`
+	Stack        string                 `json:"-"`
+	Caller       string                 `json:"-"`
+	Fields       []FieldError           `json:"fields"`
+	Public       bool                   `json:"public"`
+}
+
+// FieldError is a validation error of a single field of the request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 func (g GError) Error() string {
@@ -18,11 +50,87 @@ func (g GError) Error() string {
 	return g.Err.Error()
 }
 
+// StackTrace returns the stack recorded when the error was aborted with, either
+// by CaptureStack or on a recovered panic.
+func (g GError) StackTrace() string {
+	return g.Stack
+}
+
+// WithPublic returns a copy of the error marked as public: its hint is safe
+// to show to clients, even with PublicHintsOnly.
+func (g GError) WithPublic() GError {
+	g.Public = true
+	return g
+}
+
+// WithCode returns a copy of the error with a new status code.
+func (g GError) WithCode(code int) GError {
+	g.Code = code
+	return g
+}
+
+// WithHeader returns a copy of the error with a header added to its response.
+func (g GError) WithHeader(key, value string) GError {
+	headers := g.Headers.Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+	headers.Add(key, value)
+	g.Headers = headers
+	return g
+}
+
+// WithLink returns a copy of the error with a Link header added, e.g. to point
+// to the first and last page when a requested page is out of bounds.
+func (g GError) WithLink(rel, uri string) GError {
+	return g.WithHeader("Link", fmt.Sprintf("<%s>; rel=%q", uri, rel))
+}
+
+// WithFieldError returns a copy of the error with a field error appended,
+// e.g. WithFieldError("email", "required", "Email is required"). The field
+// errors are listed in the response body.
+func (g GError) WithFieldError(field, code, message string) GError {
+	g.Fields = append(append([]FieldError(nil), g.Fields...), FieldError{Field: field, Code: code, Message: message})
+	return g
+}
+
+// WithDetail returns a copy of the error with a structured detail appended,
+// like a gRPC QuotaFailure. The details are listed in the response body.
+func (g GError) WithDetail(detail interface{}) GError {
+	g.Details = append(append([]interface{}(nil), g.Details...), detail)
+	return g
+}
+
+// DetailedError formats the error as "[code] hint: err", omitting the empty parts.
+func (g GError) DetailedError() string {
+	detail := fmt.Sprintf("[%d]", g.Code)
+	if g.Hint != "" {
+		detail += " " + g.Hint
+	}
+	if g.Err != nil {
+		if g.Hint != "" {
+			detail += ":"
+		}
+		detail += " " + g.Err.Error()
+	}
+	return detail
+}
+
+var fingerprintPattern = regexp.MustCompile(`[0-9a-fA-F]{8}(-[0-9a-fA-F]{4}){3}-[0-9a-fA-F]{12}|0x[0-9a-fA-F]+|[0-9]+`)
+
+// Fingerprint returns a stable hash of the code and the error message with
+// numbers and IDs stripped, so similar errors can be grouped together.
+func (g GError) Fingerprint() string {
+	message := fingerprintPattern.ReplaceAllString(g.Error(), "?")
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", g.Code, message)))
+	return hex.EncodeToString(sum[:8])
+}
+
 func New(code int, err error, hint string) error {
 	return GError{
-		code,
-		err,
-		hint,
+		Code: code,
+		Err:  err,
+		Hint: hint,
 	}
 }
 
@@ -39,13 +147,33 @@ func AbortWithHint(c *gin.Context, code int, hint string) {
 }
 
 func AbortWithErrorAndHint(c *gin.Context, code int, err error, hint string) {
-	if _, ok := err.(GError); !ok {
-		err = New(code, err, hint)
+	AbortWithMeta(c, code, err, hint, nil)
+}
+
+// AbortWithMeta works like AbortWithErrorAndHint and sets meta on the pushed
+// gin.Error, so it can be read by LoggingFuncWithContext. An error repeating
+// the last one of the context is not pushed again.
+func AbortWithMeta(c *gin.Context, code int, err error, hint string, meta interface{}) {
+	gError, ok := err.(GError)
+	if !ok {
+		gError = New(code, err, hint).(GError)
+	}
+	if c.GetBool(captureCallerKey) && gError.Caller == "" {
+		gError.Caller = callerName()
 	}
+	if options, ok := c.Get(captureStackKey); ok && gError.Stack == "" {
+		gError.Stack = stackTrace(options.(stackOptions))
+	}
+	err = gError
+	warnWithoutMiddleware(c)
 	c.Abort()
+	if !c.GetBool(keepDuplicatesKey) && repeatsLastError(c, gError, meta) {
+		return
+	}
 	c.Errors = append(c.Errors, &gin.Error{
 		Err:  err,
 		Type: gin.ErrorTypePrivate,
+		Meta: meta,
 	})
 }
 
@@ -53,47 +181,812 @@ func AbortWithError(c *gin.Context, code int, err error) {
 	AbortWithErrorAndHint(c, code, err, "")
 }
 
+const (
+	logOnlyKey = "github.com/dcalsky/gerror/logOnly"
+	skipKey    = "github.com/dcalsky/gerror/skip"
+)
+
+// SkipErrorHandling opts the current request out of the middleware, which
+// then neither logs nor writes any error response.
+func SkipErrorHandling(c *gin.Context) {
+	c.Set(skipKey, true)
+}
+
+// SafeAbortWithError works like AbortWithError, but if the response has already
+// been written, the error is only logged and no error response is attempted.
+func SafeAbortWithError(c *gin.Context, code int, err error) {
+	if c.Writer.Written() {
+		c.Set(logOnlyKey, true)
+	}
+	AbortWithError(c, code, err)
+}
+
+// ErrorResponse is the response body written by the default ResponseBodyFunc.
+type ErrorResponse struct {
+	Code      int               `json:"code,omitempty"`
+	Message   string            `json:"message"`
+	Detail    string            `json:"detail,omitempty"`
+	ErrorType string            `json:"error_type,omitempty"`
+	AllErrors []string          `json:"all_errors,omitempty"`
+	Chain     []string          `json:"chain,omitempty"`
+	Errors    []string          `json:"errors,omitempty"`
+	Fields    []FieldError      `json:"fields,omitempty"`
+	Details   []interface{}     `json:"details,omitempty"`
+	Baggage   map[string]string `json:"baggage,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Timestamp string            `json:"timestamp,omitempty"`
+
+	fields           EnvelopeFields
+	numericTimestamp bool
+}
+
+// EnvelopeFields names the keys of the default response body and of the body
+// written by Success. Empty keys fall back to "code", "message", "detail",
+// "success" and "data".
+type EnvelopeFields struct {
+	MessageKey string
+	CodeKey    string
+	DetailKey  string
+	SuccessKey string
+	DataKey    string
+}
+
+func (f EnvelopeFields) withDefaults() EnvelopeFields {
+	if f.MessageKey == "" {
+		f.MessageKey = "message"
+	}
+	if f.CodeKey == "" {
+		f.CodeKey = "code"
+	}
+	if f.DetailKey == "" {
+		f.DetailKey = "detail"
+	}
+	if f.SuccessKey == "" {
+		f.SuccessKey = "success"
+	}
+	if f.DataKey == "" {
+		f.DataKey = "data"
+	}
+	return f
+}
+
+func (r ErrorResponse) MarshalJSON() ([]byte, error) {
+	return marshalFields(r.jsonFields())
+}
+
+func (r ErrorResponse) jsonFields() []jsonField {
+	keys := r.fields.withDefaults()
+	var fields []jsonField
+	if r.Code != 0 {
+		fields = append(fields, jsonField{keys.CodeKey, r.Code})
+	}
+	fields = append(fields, jsonField{keys.MessageKey, r.Message})
+	if r.Detail != "" {
+		fields = append(fields, jsonField{keys.DetailKey, r.Detail})
+	}
+	if r.ErrorType != "" {
+		fields = append(fields, jsonField{"error_type", r.ErrorType})
+	}
+	if len(r.AllErrors) > 0 {
+		fields = append(fields, jsonField{"all_errors", r.AllErrors})
+	}
+	if len(r.Chain) > 0 {
+		fields = append(fields, jsonField{"chain", r.Chain})
+	}
+	if len(r.Errors) > 0 {
+		fields = append(fields, jsonField{"errors", r.Errors})
+	}
+	if len(r.Fields) > 0 {
+		fields = append(fields, jsonField{"fields", r.Fields})
+	}
+	if len(r.Details) > 0 {
+		fields = append(fields, jsonField{"details", r.Details})
+	}
+	if len(r.Baggage) > 0 {
+		fields = append(fields, jsonField{"baggage", r.Baggage})
+	}
+	if r.Instance != "" {
+		fields = append(fields, jsonField{"instance", r.Instance})
+	}
+	if r.RequestID != "" {
+		fields = append(fields, jsonField{"request_id", r.RequestID})
+	}
+	if r.Timestamp != "" && r.numericTimestamp {
+		fields = append(fields, jsonField{"timestamp", json.Number(r.Timestamp)})
+	} else if r.Timestamp != "" {
+		fields = append(fields, jsonField{"timestamp", r.Timestamp})
+	}
+	return fields
+}
+
+// topLevelArray splits a body with several errors into an element per error.
+// The other members, like the request ID, are shared by every element.
+func topLevelArray(body ErrorResponse) []ErrorResponse {
+	if len(body.Errors) == 0 {
+		return []ErrorResponse{body}
+	}
+	elements := make([]ErrorResponse, len(body.Errors))
+	for i, err := range body.Errors {
+		elements[i] = body
+		elements[i].Message = err
+		elements[i].Errors = nil
+	}
+	return elements
+}
+
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+func marshalFields(fields []jsonField) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
 type MiddlewareOption struct {
 	ResponseBodyFunc func(code int, message string) interface{}
 	LoggingFunc      func(code int, err error)
+	// LoggingFuncWithContext takes precedence over LoggingFunc. err is the
+	// *gin.Error pushed by the abort helpers, including its Meta.
+	LoggingFuncWithContext func(c *gin.Context, code int, err error)
+	// LoggingFuncs are called after LoggingFunc, or the default logging.
+	LoggingFuncs []func(code int, err error)
+	Now          func() time.Time
+	// AfterResponseFunc is called once the error response has been written.
+	// It must not write to the response anymore.
+	AfterResponseFunc func(c *gin.Context, gerr GError)
+	NoStoreErrors     bool
+	// HidePrivateErrors keeps the message of gin errors which are not of
+	// gin.ErrorTypePublic out of the response body, like gin.ErrorLogger does.
+	HidePrivateErrors bool
+	// LogRequestBodyOn5xx adds the first bytes of the request body to the
+	// default log entry of 5xx errors.
+	LogRequestBodyOn5xx bool
+	// LogRateLimit limits the number of logged errors per fingerprint within
+	// LogRateWindow, which defaults to one minute.
+	LogRateLimit  int
+	LogRateWindow time.Duration
+	// StreamErrors writes the response body as a newline-delimited JSON line.
+	StreamErrors bool
+	// IncludeCode adds the status code to the default response body under
+	// CodeFieldName, which defaults to "code".
+	IncludeCode   bool
+	CodeFieldName string
+	// MessageFieldName is the key of the message in the default response
+	// body, which defaults to "message".
+	MessageFieldName string
+	// EnvelopeFields renames the keys of the default response body and takes
+	// precedence over CodeFieldName and MessageFieldName.
+	EnvelopeFields EnvelopeFields
+	// Messages maps a language tag to the messages used for errors without a
+	// hint by status code. The language is negotiated with the Accept-Language
+	// header and falls back to DefaultLanguage.
+	Messages        map[string]map[int]string
+	DefaultLanguage string
+	// RecentErrorsCapacity keeps the last errors in memory, see RecentErrors.
+	RecentErrorsCapacity int
+	// StripANSI removes ANSI escape codes from the logged error message.
+	StripANSI bool
+	// BaggageKeys selects the OpenTelemetry baggage entries, read from the
+	// W3C baggage header, added to the default response body and log entry.
+	BaggageKeys []string
+	// LogContextKeys selects the values set on the gin context by earlier
+	// middlewares, e.g. a user or tenant ID, added to the default log entry.
+	LogContextKeys []string
+	// Production masks the raw error details in the response body: the
+	// message of private gin errors is replaced by the status text and the
+	// list of multiple errors is left out.
+	Production bool
+	// PublicHintsOnly also masks the hints of the errors not marked with
+	// GError.WithPublic in Production, for services whose hints may hold
+	// internal details. They are replaced like the private gin errors.
+	PublicHintsOnly bool
+	// DetailVisibilityFunc decides per request whether the raw error is added
+	// to the default response body under "detail". When it returns false, the
+	// details are masked like in Production.
+	DetailVisibilityFunc func(c *gin.Context) bool
+	// ProblemJSON writes the default response body as an RFC 7807 problem
+	// document with the content type application/problem+json.
+	ProblemJSON bool
+	// HALJSON writes the default response body as a HAL document with the
+	// content type application/hal+json and a self link to the request.
+	HALJSON bool
+	// QuietCodes are logged at debug level by the default logging, instead of
+	// warning level for 4xx and error level for 5xx.
+	QuietCodes []int
+	// AlwaysBodyForServerErrors writes the status text as the message of 5xx
+	// errors without a hint, instead of the status code only. 4xx errors
+	// without a hint are still written without a body.
+	AlwaysBodyForServerErrors bool
+	// SkipLogPaths are paths whose errors aren't logged, like health checks.
+	// A path matches itself and the paths below it, so "/healthz" matches
+	// "/healthz" and "/healthz/ready".
+	SkipLogPaths []string
+	// ErrorTypeCodes maps the type of gin errors aborted without a status code
+	// to a status code. It defaults to 400 for gin.ErrorTypeBind.
+	ErrorTypeCodes map[gin.ErrorType]int
+	// ErrorPageFS serves an HTML page instead of the response body to clients
+	// accepting text/html. ErrorPageFunc returns the path of the page of a
+	// status code and defaults to "errors/{code}.html". Clients fall back to
+	// the response body when the page does not exist.
+	ErrorPageFS   fs.FS
+	ErrorPageFunc func(code int) string
+	// RecoverPanics turns panics of the following handlers into errors with the
+	// code returned by PanicCodeFunc, which defaults to 500.
+	RecoverPanics bool
+	PanicCodeFunc func(recovered interface{}) int
+	// IncludeErrorType adds the Go type of the error, e.g. "*net.OpError", to
+	// the default response body unless the details are masked.
+	IncludeErrorType bool
+	// DebugIncludeAllErrors lists all errors of the request in the default
+	// response body in gin's debug mode, not only the last one.
+	DebugIncludeAllErrors bool
+	// DefaultHints maps a status code to the message used for errors without
+	// a hint, after Messages. It also replaces the status text of masked
+	// errors.
+	DefaultHints map[int]string
+	// ProblemTypeFunc returns the type URI of a problem document from the code
+	// and the reason of the error. An empty URI falls back to "about:blank".
+	ProblemTypeFunc func(code int, reason string) string
+	// BodySizeFunc is called with the number of bytes of each error response
+	// body written by the middleware.
+	BodySizeFunc func(code int, bytes int)
+	// PublishFunc forwards every error, e.g. to a message bus. It runs in its
+	// own goroutine with a context carrying the values of the request, and its
+	// failures are logged without affecting the response.
+	PublishFunc func(ctx context.Context, gErr GError) error
+	// ShouldHandle lets errors it rejects pass through, the middleware then
+	// neither logs them nor writes any response.
+	ShouldHandle func(gErr GError) bool
+	// StatusRewriteFunc rewrites the status code of the response, e.g. to
+	// collapse unusual codes for clients. The original code is logged.
+	StatusRewriteFunc func(code int) int
+	// IncludeInstance adds InstanceID, which defaults to the hostname, to the
+	// default response body and the default logging under "instance".
+	IncludeInstance bool
+	InstanceID      string
+	// PrettyInDebug indents the response body unless Production is set, for
+	// readability in browser devtools.
+	PrettyInDebug bool
+	// CaptureCaller records the name of the function calling the abort
+	// helpers in GError.Caller, which the default logging adds as "caller".
+	// For Handle, it is the wrapped function, and for recovered panics, the
+	// function that panicked.
+	CaptureCaller bool
+	// IncludeHandlerName adds the name of the route handler, from
+	// c.HandlerName, to the default logging under "handler".
+	IncludeHandlerName bool
+	// IncludeTimestamp adds the time from Now to the default response body,
+	// so clients can correlate it with the server logs. It is formatted with
+	// the TimestampFormat layout, RFC 3339 by default, or as a number of
+	// milliseconds with TimestampEpochMillis.
+	IncludeTimestamp bool
+	TimestampFormat  string
+	// KeepDuplicateErrors keeps every error aborted with by the abort helpers.
+	// By default an error equal to the last one, e.g. in a retry loop, is not
+	// added to c.Errors again. Errors with the same code, error and hint but
+	// other headers, meta, details or field errors are kept.
+	KeepDuplicateErrors bool
+	// CSVErrors writes the message as a single-column CSV with an "error"
+	// header instead of JSON, for data export endpoints.
+	CSVErrors bool
+	// CountErrorFunc is called once per error with the status code, the
+	// request method and the route from RoutePath, e.g. to increment an
+	// OpenTelemetry counter.
+	CountErrorFunc func(ctx context.Context, code int, method, route string)
+	// UseRouteTemplate labels the errors with the route template like
+	// /users/:id instead of the raw path, to keep the cardinality of metrics
+	// low: in CountErrorFunc, RoutePath, LogfmtLoggingFunc and
+	// SlogLoggingFunc. The default logging adds it under "route".
+	UseRouteTemplate bool
+	// CodeCatalog configures the message and the log level of status codes in
+	// one place. Its messages are used like DefaultHints and take precedence
+	// over them, its levels replace the ones of the default logging.
+	CodeCatalog map[int]CodeEntry
+	// TopLevelArray writes the default response body as a bare array with an
+	// object per error of a multi-error, or a single object otherwise. The
+	// other members, like "request_id", are repeated in every object.
+	TopLevelArray bool
+	// MaxChainDepth lists up to that many levels of the chain of wrapped
+	// errors under "chain" in the default response bodies showing the detail.
+	MaxChainDepth int
+	// ExposePublicError pushes the message written to the client as a public
+	// gin.Error, for logging middlewares reading c.Errors like gin-contrib/zap.
+	ExposePublicError bool
+	// MaxBodyErrors limits the number of errors of a multi-error listed in the
+	// default response body, followed by a "+N more" entry. With TopLevelArray,
+	// it limits the number of objects, without such an entry.
+	MaxBodyErrors int
+	// GenerateRequestID generates an ID for each error with IDGenerator, which
+	// defaults to a UUID v4, unless the request has one in RequestIDHeader. It
+	// is added to the default response body and the default logging under
+	// "request_id" to correlate them, and echoed in the RequestIDHeader
+	// response header, which defaults to X-Request-ID. Without it, the ID of
+	// the request is still echoed in error responses. IDs of the request
+	// longer than 128 bytes or with other than visible ASCII characters are
+	// ignored.
+	GenerateRequestID bool
+	IDGenerator       func() string
+	RequestIDHeader   string
+	// XMLFaults writes the code and the message as a text/xml fault, like
+	// <fault><code>500</code><message>...</message></fault>, for legacy clients.
+	XMLFaults bool
+	// CaptureStack records the stack trace of the abort helpers' caller in
+	// GError.Stack. StackMaxFrames limits its number of frames and the frames
+	// of StackSkipPackages, like "github.com/gin-gonic/gin" or "runtime", are
+	// left out.
+	CaptureStack      bool
+	StackMaxFrames    int
+	StackSkipPackages []string
+}
+
+// CodeEntry is the configuration of a status code in CodeCatalog. An empty
+// Message or a zero Level keeps the default.
+type CodeEntry struct {
+	Message string
+	Level   logrus.Level
+}
+
+// debugInfo holds the parts of the default response body only shown while
+// debugging.
+type debugInfo struct {
+	detail    string
+	errorType string
+	allErrors []string
+	chain     []string
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+
+type ansiStrippedError struct {
+	err error
+}
+
+func (e ansiStrippedError) Error() string {
+	return ansiPattern.ReplaceAllString(e.err.Error(), "")
+}
+
+func (e ansiStrippedError) Unwrap() error {
+	return e.err
+}
+
+const (
+	requestBodyKey      = "github.com/dcalsky/gerror/requestBody"
+	requestBodyLogLimit = 1024
+)
+
+type requestBodyReader struct {
+	io.Reader
+	io.Closer
+}
+
+func captureRequestBody(c *gin.Context) {
+	captured, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, requestBodyLogLimit+1))
+	c.Request.Body = requestBodyReader{
+		Reader: io.MultiReader(bytes.NewReader(captured), c.Request.Body),
+		Closer: c.Request.Body,
+	}
+	if err != nil {
+		return
+	}
+	requestBody := string(captured)
+	if len(captured) > requestBodyLogLimit {
+		requestBody = string(captured[:requestBodyLogLimit]) + "..."
+	}
+	c.Set(requestBodyKey, requestBody)
+}
+
+func bodyAllowedForStatus(code int) bool {
+	switch {
+	case code >= 100 && code <= 199:
+		return false
+	case code == 204, code == 304:
+		return false
+	}
+	return true
+}
+
+func writeNDJSON(c *gin.Context, code int, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		logrus.Errorln(err)
+		c.Status(code)
+		return
+	}
+	c.Data(code, "application/x-ndjson", append(data, '\n'))
+}
+
+func writeJSON(c *gin.Context, code int, contentType string, body interface{}, indent bool) {
+	var data []byte
+	var err error
+	if indent {
+		data, err = json.MarshalIndent(body, "", "    ")
+	} else {
+		data, err = json.Marshal(body)
+	}
+	if err != nil {
+		logrus.Errorln(err)
+		c.Status(code)
+		return
+	}
+	c.Data(code, contentType, data)
+}
+
+// resolveError returns the last error of an aborted request as a GError.
+func (option MiddlewareOption) resolveError(c *gin.Context) (GError, *gin.Error, bool) {
+	lastError := c.Errors.Last()
+	if !c.IsAborted() || lastError == nil {
+		return GError{}, nil, false
+	}
+	gError, ok := lastError.Err.(GError)
+	if !ok {
+		gError = GError{
+			Code: c.Writer.Status(),
+			Err:  lastError.Err,
+		}
+		if gError.Code == http.StatusOK && !c.Writer.Written() {
+			for errorType, code := range option.ErrorTypeCodes {
+				if lastError.IsType(errorType) {
+					gError.Code = code
+				}
+			}
+		}
+		if !option.HidePrivateErrors || lastError.IsType(gin.ErrorTypePublic) {
+			gError.Hint = lastError.Error()
+		}
+	}
+	return gError, lastError, true
 }
 
 func Middleware(option MiddlewareOption) gin.HandlerFunc {
-	if option.ResponseBodyFunc == nil {
-		option.ResponseBodyFunc = func(code int, message string) interface{} {
-			if message == "" {
+	option = option.withDefaults()
+	envelopeFields := option.EnvelopeFields
+	if envelopeFields.CodeKey == "" {
+		envelopeFields.CodeKey = option.CodeFieldName
+	}
+	if envelopeFields.MessageKey == "" {
+		envelopeFields.MessageKey = option.MessageFieldName
+	}
+	// errorResponse builds the default response body, or returns nil when
+	// there is nothing to write. The "+N more" marker is left out of bodies
+	// split into a top-level array.
+	errorResponse := func(c *gin.Context, gError GError, message string, debug debugInfo, topLevel bool) *ErrorResponse {
+		if message == "" && len(gError.Errors) == 0 && len(gError.Fields) == 0 && len(gError.Details) == 0 {
+			return nil
+		}
+		body := &ErrorResponse{
+			Message: message,
+			fields:  envelopeFields,
+		}
+		if option.IncludeCode {
+			body.Code = gError.Code
+		}
+		for _, err := range gError.Errors {
+			body.Errors = append(body.Errors, err.Error())
+		}
+		if option.MaxBodyErrors > 0 && len(body.Errors) > option.MaxBodyErrors {
+			more := len(body.Errors) - option.MaxBodyErrors
+			body.Errors = body.Errors[:option.MaxBodyErrors]
+			if !topLevel {
+				body.Errors = append(body.Errors, fmt.Sprintf("+%d more", more))
+			}
+		}
+		body.Fields = gError.Fields
+		body.Details = gError.Details
+		body.Detail = debug.detail
+		body.ErrorType = debug.errorType
+		body.AllErrors = debug.allErrors
+		body.Chain = debug.chain
+		body.Baggage = baggageValues(c, option.BaggageKeys)
+		if option.IncludeInstance {
+			body.Instance = option.InstanceID
+		}
+		body.RequestID = c.GetString(requestIDKey)
+		if option.IncludeTimestamp {
+			body.Timestamp, body.numericTimestamp = formatTimestamp(option.Now(), option.TimestampFormat)
+		}
+		return body
+	}
+	responseBody := func(c *gin.Context, gError GError, message string, debug debugInfo) interface{} {
+		return option.ResponseBodyFunc(gError.Code, message)
+	}
+	if option.ResponseBodyFunc == nil && option.ProblemJSON {
+		responseBody = func(c *gin.Context, gError GError, message string, debug debugInfo) interface{} {
+			return newProblem(c, gError, message, option.ProblemTypeFunc)
+		}
+	} else if option.ResponseBodyFunc == nil && option.HALJSON {
+		responseBody = func(c *gin.Context, gError GError, message string, debug debugInfo) interface{} {
+			body := errorResponse(c, gError, message, debug, false)
+			if body == nil {
 				return nil
 			}
-			return gin.H{
-				"message": message,
+			return newHALError(c, *body)
+		}
+	} else if option.ResponseBodyFunc == nil {
+		responseBody = func(c *gin.Context, gError GError, message string, debug debugInfo) interface{} {
+			body := errorResponse(c, gError, message, debug, option.TopLevelArray)
+			if body == nil {
+				return nil
 			}
+			if option.TopLevelArray {
+				return topLevelArray(*body)
+			}
+			return *body
 		}
 	}
-	if option.LoggingFunc == nil {
-		option.LoggingFunc = func(code int, err error) {
+	logging := option.LoggingFuncWithContext
+	if logging == nil && option.LoggingFunc != nil {
+		logging = func(c *gin.Context, code int, err error) {
+			option.LoggingFunc(code, err)
+		}
+	}
+	if logging == nil {
+		logging = func(c *gin.Context, code int, err error) {
+			level := logrus.WarnLevel
 			if code >= 500 {
-				logrus.Errorln(err)
+				level = logrus.ErrorLevel
+			} else if code < 400 {
+				return
+			}
+			for _, quietCode := range option.QuietCodes {
+				if code == quietCode {
+					level = logrus.DebugLevel
+				}
+			}
+			if entry := option.CodeCatalog[code]; entry.Level != logrus.PanicLevel {
+				level = entry.Level
+			}
+			entry := logrus.WithTime(option.Now())
+			if requestBody, ok := c.Get(requestBodyKey); ok && code >= 500 {
+				entry = entry.WithField("request_body", requestBody)
+			}
+			for key, value := range baggageValues(c, option.BaggageKeys) {
+				entry = entry.WithField(key, value)
+			}
+			for _, key := range option.LogContextKeys {
+				if value, ok := c.Get(key); ok {
+					entry = entry.WithField(key, value)
+				}
+			}
+			if option.IncludeInstance {
+				entry = entry.WithField("instance", option.InstanceID)
+			}
+			if requestID := c.GetString(requestIDKey); requestID != "" {
+				entry = entry.WithField("request_id", requestID)
+			}
+			var gError GError
+			if errors.As(err, &gError) && gError.Caller != "" {
+				entry = entry.WithField("caller", gError.Caller)
+			}
+			if option.UseRouteTemplate {
+				entry = entry.WithField("route", RoutePath(c))
 			}
+			if option.IncludeHandlerName {
+				entry = entry.WithField("handler", c.HandlerName())
+			}
+			entry.Logln(level, err)
 		}
 	}
+	if option.RecentErrorsCapacity > 0 {
+		recentErrors.resize(option.RecentErrorsCapacity)
+	}
+	var limiter *logLimiter
+	if option.LogRateLimit > 0 {
+		limiter = newLogLimiter(option.LogRateLimit, option.LogRateWindow)
+	}
 	return func(c *gin.Context) {
-		c.Next()
-		lastError := c.Errors.Last()
-		if c.IsAborted() && lastError != nil {
-			var message string
-			code := c.Writer.Status()
-			if gError, ok := lastError.Err.(GError); ok {
-				code = gError.Code
-				message = gError.Hint
-			} else {
-				message = lastError.Error()
-			}
-			option.LoggingFunc(code, lastError)
-			body := option.ResponseBodyFunc(code, message)
-			if body == nil {
-				c.Status(code)
-			} else {
-				c.JSON(code, body)
+		c.Set(middlewareKey, true)
+		c.Set(envelopeKey, envelope{fields: envelopeFields, includeCode: option.IncludeCode})
+		if option.CaptureCaller {
+			c.Set(captureCallerKey, true)
+		}
+		if option.CaptureStack {
+			c.Set(captureStackKey, stackOptions{maxFrames: option.StackMaxFrames, skipPackages: option.StackSkipPackages})
+		}
+		if option.KeepDuplicateErrors {
+			c.Set(keepDuplicatesKey, true)
+		}
+		if option.UseRouteTemplate {
+			c.Set(routeTemplateKey, true)
+		}
+		if option.LogRequestBodyOn5xx && c.Request.Body != nil {
+			captureRequestBody(c)
+		}
+		if option.RecoverPanics {
+			nextRecovering(c, option.PanicCodeFunc)
+		} else {
+			c.Next()
+		}
+		if c.GetBool(skipKey) {
+			return
+		}
+		gError, lastError, ok := option.resolveError(c)
+		if ok && option.ShouldHandle != nil && !option.ShouldHandle(gError) {
+			return
+		}
+		if ok {
+			code := gError.Code
+			requestID := c.GetHeader(option.RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = ""
+			}
+			if option.GenerateRequestID {
+				if requestID == "" {
+					requestID = option.IDGenerator()
+				}
+				c.Set(requestIDKey, requestID)
+			}
+			if requestID != "" {
+				c.Header(option.RequestIDHeader, requestID)
+			}
+			if option.RecentErrorsCapacity > 0 {
+				recentErrors.add(option.Now(), gError)
+			}
+			if option.PublishFunc != nil {
+				publish(c.Request.Context(), option.PublishFunc, gError)
+			}
+			if option.CountErrorFunc != nil {
+				option.CountErrorFunc(c.Request.Context(), code, c.Request.Method, RoutePath(c))
+			}
+			if clientGone(c, gError.Err) {
+				logrus.WithTime(option.Now()).Debugf("gerror: client is gone: %v", lastError)
+			} else if !matchesPath(c.Request.URL.Path, option.SkipLogPaths) && (limiter == nil || limiter.allow(gError.Fingerprint(), option.Now())) {
+				var logError error = lastError
+				if option.StripANSI {
+					logError = ansiStrippedError{lastError}
+				}
+				logging(c, code, logError)
+				for _, loggingFunc := range option.LoggingFuncs {
+					loggingFunc(code, logError)
+				}
+			}
+			// An abort without error and hint keeps the body already written by
+			// the handler.
+			keepBody := c.Writer.Written() && gError.Err == nil && gError.Hint == ""
+			if !c.GetBool(logOnlyKey) && !keepBody {
+				if status := c.Writer.Status(); gin.IsDebugging() && status != http.StatusOK && status != code {
+					logrus.Warnf("gerror: status code %d of the error differs from the status code %d set on the response", code, status)
+				}
+				if option.StatusRewriteFunc != nil {
+					code = option.StatusRewriteFunc(code)
+				}
+				if option.NoStoreErrors {
+					c.Header("Cache-Control", "no-store")
+				}
+				for key, values := range gError.Headers {
+					for _, value := range values {
+						c.Writer.Header().Add(key, value)
+					}
+				}
+				showDetail, masked := false, option.Production
+				if option.DetailVisibilityFunc != nil {
+					showDetail = option.DetailVisibilityFunc(c)
+					masked = !showDetail
+				}
+				bodyError := gError
+				bodyError.Code = code
+				message := gError.Hint
+				if masked {
+					if gErr, ok := lastError.Err.(GError); (!ok || (option.PublicHintsOnly && !gErr.Public)) && !lastError.IsType(gin.ErrorTypePublic) {
+						message = http.StatusText(code)
+						if hint, ok := option.defaultHint(code); ok {
+							message = hint
+						}
+					}
+					bodyError.Errors = nil
+				}
+				if message == "" && option.Messages != nil {
+					message = option.catalogMessage(c, code)
+				}
+				if message == "" {
+					message, _ = option.defaultHint(code)
+				}
+				if message == "" && option.AlwaysBodyForServerErrors && code >= 500 {
+					message = http.StatusText(code)
+				}
+				var debug debugInfo
+				if showDetail {
+					debug.detail = gError.Error()
+					for err := gError.Err; err != nil && len(debug.chain) < option.MaxChainDepth; err = errors.Unwrap(err) {
+						debug.chain = append(debug.chain, err.Error())
+					}
+				}
+				if option.IncludeErrorType && !masked && gError.Err != nil {
+					debug.errorType = reflect.TypeOf(gError.Err).String()
+				}
+				if option.DebugIncludeAllErrors && !masked && gin.IsDebugging() {
+					for _, err := range c.Errors {
+						if gErr, ok := err.Err.(GError); ok {
+							debug.allErrors = append(debug.allErrors, gErr.DetailedError())
+						} else {
+							debug.allErrors = append(debug.allErrors, err.Error())
+						}
+					}
+				}
+				var body interface{}
+				if multi, ok := lastError.Meta.(multiStatus); ok && bodyAllowedForStatus(code) {
+					body = newMultiStatusResponse(multi.results)
+				} else if verbatim, ok := lastError.Meta.(verbatimBody); ok && bodyAllowedForStatus(code) {
+					body = verbatim.body
+				} else if bodyAllowedForStatus(code) {
+					body = responseBody(c, bodyError, message, debug)
+				}
+				body = problemWithStatus(body, code)
+				pretty := option.PrettyInDebug && !option.Production
+				renderer := negotiatedRenderer(c)
+				sizeBefore := c.Writer.Size()
+				if sizeBefore < 0 {
+					sizeBefore = 0
+				}
+				write := func() {
+					switch {
+					case option.ErrorPageFS != nil && bodyAllowedForStatus(code) && writeErrorPage(c, option.ErrorPageFS, option.ErrorPageFunc, code):
+					case body == nil:
+						c.Status(code)
+					case option.CSVErrors:
+						writeCSV(c, code, message)
+					case option.XMLFaults:
+						writeXMLFault(c, code, message)
+					case option.StreamErrors:
+						writeNDJSON(c, code, body)
+					case option.ProblemJSON:
+						writeJSON(c, code, "application/problem+json", body, pretty)
+					case option.HALJSON:
+						writeJSON(c, code, "application/hal+json", body, pretty)
+					case renderer != nil:
+						renderer(c, code, body)
+					default:
+						writeJSON(c, code, "application/json; charset=utf-8", body, pretty)
+					}
+				}
+				func() {
+					defer recoverClientGone()
+					if gError.ReasonPhrase != "" && reasonPhraseSupported(c) {
+						writeWithReasonPhrase(c, code, gError.ReasonPhrase, write)
+					} else {
+						write()
+					}
+				}()
+				if option.BodySizeFunc != nil {
+					if size := c.Writer.Size(); size > sizeBefore {
+						option.BodySizeFunc(code, size-sizeBefore)
+					} else {
+						option.BodySizeFunc(code, 0)
+					}
+				}
+				if option.ExposePublicError {
+					publicMessage := message
+					if publicMessage == "" {
+						publicMessage = http.StatusText(code)
+					}
+					c.Errors = append(c.Errors, &gin.Error{
+						Err:  errors.New(publicMessage),
+						Type: gin.ErrorTypePublic,
+					})
+				}
+			}
+			if option.AfterResponseFunc != nil {
+				option.AfterResponseFunc(c, gError)
 			}
 		}
 	}
@@ -2,16 +2,22 @@ package gerror
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 type CustomFormatter struct{}
@@ -135,6 +141,7 @@ func TestDefaultOptionFunction(t *testing.T) {
 		res := performRequest(router, "GET", path)
 		// It uses the last error
 		assert.Equal(t, res.Code, 400)
+		assert.Equal(t, "custom error", readLog(t))
 		body := parseBody(t, res)
 		assert.Equal(t, "error2", body["message"])
 	})
@@ -197,3 +204,1270 @@ func TestCustomResponseBodyFunc(t *testing.T) {
 		assert.Len(t, _bytes, 0)
 	})
 }
+
+func TestCustomClock(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		Now: func() time.Time {
+			return now
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithError(c, 500, errors.New("clock error"))
+	})
+	readLog(t)
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, res.Code, 500)
+	assert.Equal(t, "clock error", readLog(t))
+	assert.Equal(t, now, hook.LastEntry().Time)
+}
+
+func TestFingerprint(t *testing.T) {
+	err1 := New(404, fmt.Errorf("user %d not found", 42), "").(GError)
+	err2 := New(404, fmt.Errorf("user %d not found", 1337), "").(GError)
+	err3 := New(404, errors.New("user 3f2504e0-4f89-11d3-9a0c-0305e82c3301 not found"), "").(GError)
+	assert.Equal(t, err1.Fingerprint(), err2.Fingerprint())
+	assert.Equal(t, err1.Fingerprint(), err3.Fingerprint())
+	assert.Len(t, err1.Fingerprint(), 16)
+
+	err4 := New(500, fmt.Errorf("user %d not found", 42), "").(GError)
+	err5 := New(404, errors.New("order not found"), "").(GError)
+	assert.NotEqual(t, err1.Fingerprint(), err4.Fingerprint())
+	assert.NotEqual(t, err1.Fingerprint(), err5.Fingerprint())
+}
+
+func TestAfterResponseFunction(t *testing.T) {
+	var called bool
+	var resolved GError
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		AfterResponseFunc: func(c *gin.Context, gerr GError) {
+			called = true
+			resolved = gerr
+			assert.True(t, c.Writer.Written())
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithErrorAndHint(c, 400, errors.New("after error"), "after hint")
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, res.Code, 400)
+	assert.Equal(t, "after error", readLog(t))
+	assert.True(t, called)
+	assert.Equal(t, 400, resolved.Code)
+	assert.Equal(t, "after hint", resolved.Hint)
+	assert.EqualError(t, resolved.Err, "after error")
+}
+
+func TestStatusMismatchWarning(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	t.Run("mismatch", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			c.Status(409)
+			AbortWithHint(c, 400, "mismatch")
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 400)
+		assert.Equal(t, "gerror: status code 400 of the error differs from the status code 409 set on the response", readLog(t))
+	})
+	t.Run("match", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			c.Status(400)
+			AbortWithHint(c, 400, "match")
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 400)
+		assert.Equal(t, "", readLog(t))
+	})
+}
+
+func TestDetailedError(t *testing.T) {
+	err := errors.New("detailed error")
+	assert.Equal(t, "[500] custom hint: detailed error", New(500, err, "custom hint").(GError).DetailedError())
+	assert.Equal(t, "[500] detailed error", New(500, err, "").(GError).DetailedError())
+	assert.Equal(t, "[400] custom hint", NewHint(400, "custom hint").(GError).DetailedError())
+	assert.Equal(t, "[404]", NewEmpty(404).(GError).DetailedError())
+	assert.Equal(t, "detailed error", New(500, err, "custom hint").Error())
+}
+
+func TestSafeAbortWithError(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	t.Run("written response", func(t *testing.T) {
+		var ginLog bytes.Buffer
+		defaultWriter := gin.DefaultWriter
+		gin.DefaultWriter = &ginLog
+		defer func() {
+			gin.DefaultWriter = defaultWriter
+		}()
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			c.String(200, "partial body")
+			SafeAbortWithError(c, 500, errors.New("late error"))
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 200)
+		assert.Equal(t, "partial body", res.Body.String())
+		assert.Equal(t, "late error", readLog(t))
+		assert.NotContains(t, ginLog.String(), "Headers were already written")
+	})
+	t.Run("pending response", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			SafeAbortWithError(c, 500, New(500, errors.New("early error"), "early hint"))
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 500)
+		assert.Equal(t, "early error", readLog(t))
+		body := parseBody(t, res)
+		assert.Equal(t, "early hint", body["message"])
+	})
+}
+
+func TestNoStoreErrors(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		router := gin.New()
+		router.Use(Middleware(MiddlewareOption{NoStoreErrors: true}))
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithHint(c, 400, "bad input")
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 400)
+		assert.Equal(t, "no-store", res.Header().Get("Cache-Control"))
+	})
+	t.Run("disabled", func(t *testing.T) {
+		router := gin.New()
+		router.Use(Middleware(MiddlewareOption{}))
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithHint(c, 404, "not found")
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 404)
+		assert.Empty(t, res.Header().Get("Cache-Control"))
+	})
+}
+
+func TestHidePrivateErrors(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{HidePrivateErrors: true}))
+	t.Run("public error", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			_ = c.Error(errors.New("public error")).SetType(gin.ErrorTypePublic)
+			c.AbortWithStatus(400)
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 400)
+		assert.Equal(t, "public error", readLog(t))
+		body := parseBody(t, res)
+		assert.Equal(t, "public error", body["message"])
+	})
+	t.Run("private error", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			_ = c.Error(errors.New("public error")).SetType(gin.ErrorTypePublic)
+			_ = c.AbortWithError(500, errors.New("private error"))
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 500)
+		assert.Equal(t, "private error", readLog(t))
+		assert.NotContains(t, res.Body.String(), "private error")
+		assert.NotContains(t, res.Body.String(), "public error")
+	})
+	t.Run("gerror", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithErrorAndHint(c, 500, errors.New("internal error"), "custom hint")
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 500)
+		assert.Equal(t, "internal error", readLog(t))
+		body := parseBody(t, res)
+		assert.Equal(t, "custom hint", body["message"])
+	})
+}
+
+func TestErrorResponse(t *testing.T) {
+	expected, err := json.Marshal(gin.H{"message": "custom hint"})
+	assert.NoError(t, err)
+	actual, err := json.Marshal(ErrorResponse{Message: "custom hint"})
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(actual))
+
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 400, "custom hint")
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, res.Code, 400)
+	assert.Equal(t, string(expected), res.Body.String())
+}
+
+func TestLogRequestBodyOn5xx(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{LogRequestBodyOn5xx: true}))
+	requestBody := strings.Repeat("a", 1000) + strings.Repeat("b", 100)
+	path := getTestPath()
+	router.POST(path, func(c *gin.Context) {
+		data, err := c.GetRawData()
+		assert.NoError(t, err)
+		assert.Equal(t, requestBody, string(data))
+		AbortWithError(c, 500, errors.New("body error"))
+	})
+	req, _ := http.NewRequest("POST", path, strings.NewReader(requestBody))
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, res.Code, 500)
+	assert.Equal(t, "body error", readLog(t))
+	assert.Equal(t, requestBody[:1024]+"...", hook.LastEntry().Data["request_body"])
+}
+
+func TestWithCode(t *testing.T) {
+	origin := New(502, errors.New("upstream error"), "upstream failed").(GError)
+	remapped := origin.WithCode(503)
+	assert.Equal(t, 502, origin.Code)
+	assert.Equal(t, 503, remapped.Code)
+	assert.Equal(t, origin.Err, remapped.Err)
+	assert.Equal(t, origin.Hint, remapped.Hint)
+}
+
+func TestAbortWithMeta(t *testing.T) {
+	var loggedCode int
+	var loggedMeta interface{}
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		LoggingFuncWithContext: func(c *gin.Context, code int, err error) {
+			loggedCode = code
+			var ginErr *gin.Error
+			if errors.As(err, &ginErr) {
+				loggedMeta = ginErr.Meta
+			}
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithMeta(c, 409, errors.New("meta error"), "conflict", gin.H{"order": 42})
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, res.Code, 409)
+	assert.Equal(t, 409, loggedCode)
+	assert.Equal(t, gin.H{"order": 42}, loggedMeta)
+	body := parseBody(t, res)
+	assert.Equal(t, "conflict", body["message"])
+}
+
+func TestStreamErrors(t *testing.T) {
+	router := gin.New()
+	stream := router.Group("/stream", Middleware(MiddlewareOption{StreamErrors: true}))
+	stream.GET("/events", func(c *gin.Context) {
+		AbortWithHint(c, 400, "bad cursor")
+	})
+	api := router.Group("/api", Middleware(MiddlewareOption{}))
+	api.GET("/events", func(c *gin.Context) {
+		AbortWithHint(c, 400, "bad cursor")
+	})
+
+	res := performRequest(router, "GET", "/stream/events")
+	assert.Equal(t, res.Code, 400)
+	assert.Equal(t, "application/x-ndjson", res.Header().Get("Content-Type"))
+	line := res.Body.String()
+	assert.True(t, strings.HasSuffix(line, "\n"))
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(strings.TrimSuffix(line, "\n")), &body))
+	assert.Equal(t, "bad cursor", body["message"])
+
+	res = performRequest(router, "GET", "/api/events")
+	assert.Equal(t, res.Code, 400)
+	assert.Equal(t, "application/json; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.False(t, strings.HasSuffix(res.Body.String(), "\n"))
+	body = parseBody(t, res)
+	assert.Equal(t, "bad cursor", body["message"])
+}
+
+func TestSkipErrorHandling(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		SkipErrorHandling(c)
+		c.String(503, "unhealthy")
+		AbortWithError(c, 503, errors.New("database is down"))
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, res.Code, 503)
+	assert.Equal(t, "unhealthy", res.Body.String())
+	assert.Equal(t, "", readLog(t))
+}
+
+func TestIncludeCode(t *testing.T) {
+	t.Run("default field name", func(t *testing.T) {
+		router := gin.New()
+		router.Use(Middleware(MiddlewareOption{IncludeCode: true}))
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithHint(c, 400, "bad input")
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 400)
+		assert.Equal(t, `{"code":400,"message":"bad input"}`, res.Body.String())
+	})
+	t.Run("custom field name", func(t *testing.T) {
+		router := gin.New()
+		router.Use(Middleware(MiddlewareOption{IncludeCode: true, CodeFieldName: "errorCode"}))
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithHint(c, 400, "bad input")
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 400)
+		body := parseBody(t, res)
+		assert.Equal(t, float64(400), body["errorCode"])
+		assert.NotContains(t, body, "code")
+	})
+	t.Run("excluded", func(t *testing.T) {
+		router := gin.New()
+		router.Use(Middleware(MiddlewareOption{CodeFieldName: "errorCode"}))
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithHint(c, 400, "bad input")
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 400)
+		assert.Equal(t, `{"message":"bad input"}`, res.Body.String())
+	})
+}
+
+func TestStripANSI(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{StripANSI: true}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		_ = c.AbortWithError(500, errors.New("\x1b[31mcolored\x1b[0m error"))
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, res.Code, 500)
+	assert.Equal(t, "colored error", readLog(t))
+	body := parseBody(t, res)
+	assert.Equal(t, "\x1b[31mcolored\x1b[0m error", body["message"])
+}
+
+func TestNoBodyStatus(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		ResponseBodyFunc: func(code int, message string) interface{} {
+			return gin.H{"message": message}
+		},
+		StreamErrors: true,
+	}))
+	for _, code := range []int{204, 304} {
+		path := getTestPath()
+		code := code
+		router.GET(path, func(c *gin.Context) {
+			AbortWithHint(c, code, "no body")
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, code)
+		assert.Equal(t, 0, res.Body.Len())
+	}
+}
+
+func TestLoggingFuncs(t *testing.T) {
+	var calls []string
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		LoggingFunc: func(code int, err error) {
+			calls = append(calls, fmt.Sprintf("single %d %v", code, err))
+		},
+		LoggingFuncs: []func(code int, err error){
+			func(code int, err error) {
+				calls = append(calls, fmt.Sprintf("first %d %v", code, err))
+			},
+			func(code int, err error) {
+				calls = append(calls, fmt.Sprintf("second %d %v", code, err))
+			},
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithError(c, 502, errors.New("chained error"))
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, res.Code, 502)
+	assert.Equal(t, []string{
+		"single 502 chained error",
+		"first 502 chained error",
+		"second 502 chained error",
+	}, calls)
+}
+
+func TestStableBodyOrder(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		IncludeCode:      true,
+		CodeFieldName:    "status",
+		MessageFieldName: "error",
+		BaggageKeys:      []string{"tenant", "user", "region"},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		merr := &fakeMultiError{Errors: []error{errors.New("a"), errors.New("b")}}
+		AbortWithErrorAndHint(c, 400, NewFromMultiError(422, merr), "")
+	})
+	expected := `{"status":422,"error":"","errors":["a","b"],"baggage":{"region":"eu","tenant":"acme","user":"42"}}`
+	for i := 0; i < 20; i++ {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("baggage", "user=42,tenant=acme,region=eu")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, expected, res.Body.String())
+		assert.Equal(t, "a; b", readLog(t))
+	}
+}
+
+func TestEnvelopeFields(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		IncludeCode:      true,
+		CodeFieldName:    "errorCode",
+		MessageFieldName: "error",
+		EnvelopeFields: EnvelopeFields{
+			MessageKey: "msg",
+			CodeKey:    "status",
+			DetailKey:  "cause",
+		},
+		DetailVisibilityFunc: func(c *gin.Context) bool {
+			return true
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithErrorAndHint(c, 500, errors.New("sql: connection refused"), "Internal error")
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 500, res.Code)
+	assert.Equal(t, `{"status":500,"msg":"Internal error","cause":"sql: connection refused"}`, res.Body.String())
+	assert.Equal(t, "sql: connection refused", readLog(t))
+}
+
+func TestDetailVisibility(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		DetailVisibilityFunc: func(c *gin.Context) bool {
+			return c.GetHeader("X-Role") == "admin"
+		},
+	}))
+	gErrorPath := getTestPath()
+	router.GET(gErrorPath, func(c *gin.Context) {
+		AbortWithErrorAndHint(c, 500, errors.New("sql: connection refused"), "Internal error")
+	})
+	ginErrorPath := getTestPath()
+	router.GET(ginErrorPath, func(c *gin.Context) {
+		_ = c.AbortWithError(500, errors.New("sql: connection refused"))
+	})
+	request := func(path, role string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("X-Role", role)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		readLog(t)
+		return res
+	}
+
+	t.Run("admin", func(t *testing.T) {
+		res := request(gErrorPath, "admin")
+		assert.Equal(t, `{"message":"Internal error","detail":"sql: connection refused"}`, res.Body.String())
+		res = request(ginErrorPath, "admin")
+		assert.Equal(t, `{"message":"sql: connection refused","detail":"sql: connection refused"}`, res.Body.String())
+	})
+	t.Run("user", func(t *testing.T) {
+		res := request(gErrorPath, "user")
+		assert.Equal(t, `{"message":"Internal error"}`, res.Body.String())
+		res = request(ginErrorPath, "user")
+		assert.Equal(t, `{"message":"Internal Server Error"}`, res.Body.String())
+	})
+}
+
+func TestDuplicateErrors(t *testing.T) {
+	errTimeout := errors.New("timeout")
+	for _, keep := range []bool{false, true} {
+		var count int
+		router := gin.New()
+		router.Use(Middleware(MiddlewareOption{KeepDuplicateErrors: keep}))
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			for i := 0; i < 2; i++ {
+				AbortWithErrorAndHint(c, 504, errTimeout, "Upstream timeout")
+			}
+			AbortWithError(c, 504, NewHint(504, "Upstream timeout"))
+			AbortWithError(c, 504, NewHint(504, "Upstream timeout"))
+			count = len(c.Errors)
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, 504, res.Code)
+		readLog(t)
+		if keep {
+			assert.Equal(t, 4, count)
+		} else {
+			assert.Equal(t, 2, count)
+		}
+	}
+}
+
+func TestDuplicateErrorsWithDifferentContent(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	var count int
+	router.GET(path, func(c *gin.Context) {
+		err := NewHint(503, "Down for maintenance").(GError)
+		AbortWithError(c, 503, err)
+		AbortWithError(c, 503, err.WithHeader("Retry-After", "120"))
+		AbortWithError(c, 503, err.WithHeader("Retry-After", "120"))
+		AbortWithError(c, 503, err.WithHeader("Retry-After", "120").WithMeta("window", "02:00"))
+		AbortWithError(c, 503, err.WithHeader("Retry-After", "120").WithMeta("window", "02:00").WithFieldError("region", "closed", "Region is closed"))
+		AbortWithError(c, 503, err.WithHeader("Retry-After", "120").WithMeta("window", "02:00").WithFieldError("region", "closed", "Region is closed").WithDetail("eu-west-1"))
+		AbortWithMeta(c, 503, nil, "Down for maintenance", map[string]int{"attempt": 1})
+		AbortWithMeta(c, 503, nil, "Down for maintenance", map[string]int{"attempt": 2})
+		count = len(c.Errors)
+	})
+	res := performRequest(router, "GET", path)
+	readLog(t)
+	assert.Equal(t, 503, res.Code)
+	assert.Equal(t, 7, count)
+}
+
+type valueError struct {
+	error
+}
+
+func TestDuplicateUncomparableErrors(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	var count int
+	router.GET(path, func(c *gin.Context) {
+		AbortWithError(c, 500, valueError{NewHint(400, "Invalid user")})
+		AbortWithError(c, 500, valueError{NewHint(400, "Invalid user")})
+		count = len(c.Errors)
+	})
+	res := performRequest(router, "GET", path)
+	readLog(t)
+	assert.Equal(t, 500, res.Code)
+	assert.Equal(t, 2, count)
+}
+
+func TestDebugIncludeAllErrors(t *testing.T) {
+	defer gin.SetMode(gin.DebugMode)
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{DebugIncludeAllErrors: true}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithErrorAndHint(c, 400, errors.New("missing name"), "Invalid user")
+		AbortWithHint(c, 404, "Team not found")
+		AbortWithHint(c, 409, "User already exists")
+	})
+
+	gin.SetMode(gin.DebugMode)
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 409, res.Code)
+	assert.Equal(t, `{"message":"User already exists","all_errors":["[400] Invalid user: missing name","[404] Team not found","[409] User already exists"]}`, res.Body.String())
+	readLog(t)
+
+	gin.SetMode(gin.ReleaseMode)
+	res = performRequest(router, "GET", path)
+	assert.Equal(t, 409, res.Code)
+	assert.Equal(t, `{"message":"User already exists"}`, res.Body.String())
+	readLog(t)
+}
+
+func TestDefaultHints(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		Production: true,
+		DefaultHints: map[int]string{
+			429: "Too many requests, try again later",
+			503: "Down for maintenance",
+		},
+	}))
+	rateLimitedPath := getTestPath()
+	router.GET(rateLimitedPath, func(c *gin.Context) {
+		AbortWithError(c, 429, errors.New("quota of user 42 exceeded"))
+	})
+	unmappedPath := getTestPath()
+	router.GET(unmappedPath, func(c *gin.Context) {
+		AbortWithError(c, 418, errors.New("no coffee"))
+	})
+	maskedPath := getTestPath()
+	router.GET(maskedPath, func(c *gin.Context) {
+		_ = c.AbortWithError(503, errors.New("database is migrating"))
+	})
+
+	res := performRequest(router, "GET", rateLimitedPath)
+	assert.Equal(t, 429, res.Code)
+	assert.Equal(t, `{"message":"Too many requests, try again later"}`, res.Body.String())
+	assert.Equal(t, "quota of user 42 exceeded", readLog(t))
+
+	res = performRequest(router, "GET", unmappedPath)
+	assert.Equal(t, 418, res.Code)
+	assert.Empty(t, res.Body.String())
+	assert.Equal(t, "no coffee", readLog(t))
+
+	res = performRequest(router, "GET", maskedPath)
+	assert.Equal(t, 503, res.Code)
+	assert.Equal(t, `{"message":"Down for maintenance"}`, res.Body.String())
+	assert.Equal(t, "database is migrating", readLog(t))
+}
+
+func TestBodySizeFunc(t *testing.T) {
+	var sizes []int
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		BodySizeFunc: func(code int, bytes int) {
+			sizes = append(sizes, code, bytes)
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 400, "Invalid email address")
+	})
+	emptyPath := getTestPath()
+	router.GET(emptyPath, func(c *gin.Context) {
+		AbortWithError(c, 500, errors.New("empty"))
+	})
+
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, []int{400, res.Body.Len()}, sizes)
+	assert.Equal(t, len(`{"message":"Invalid email address"}`), res.Body.Len())
+	readLog(t)
+
+	sizes = nil
+	res = performRequest(router, "GET", emptyPath)
+	assert.Equal(t, []int{500, 0}, sizes)
+	assert.Equal(t, 0, res.Body.Len())
+	assert.Equal(t, "empty", readLog(t))
+}
+
+func TestShouldHandle(t *testing.T) {
+	errAlreadyHandled := errors.New("already handled")
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		ShouldHandle: func(gErr GError) bool {
+			return !errors.Is(gErr.Err, errAlreadyHandled)
+		},
+	}))
+	handledPath := getTestPath()
+	router.GET(handledPath, func(c *gin.Context) {
+		AbortWithErrorAndHint(c, 500, errAlreadyHandled, "Internal error")
+	})
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithErrorAndHint(c, 500, errors.New("failure"), "Internal error")
+	})
+
+	res := performRequest(router, "GET", handledPath)
+	assert.Equal(t, 200, res.Code)
+	assert.Empty(t, res.Body.String())
+	assert.Empty(t, readLog(t))
+
+	res = performRequest(router, "GET", path)
+	assert.Equal(t, 500, res.Code)
+	assert.Equal(t, `{"message":"Internal error"}`, res.Body.String())
+	assert.Equal(t, "failure", readLog(t))
+}
+
+func TestWithLink(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		err := NewHint(400, "Page 12 is out of bounds").(GError).
+			WithLink("first", "/users?page=1").
+			WithLink("last", "/users?page=10").
+			WithHeader("X-Total-Count", "95")
+		AbortWithError(c, 400, err)
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 400, res.Code)
+	assert.Equal(t, []string{`</users?page=1>; rel="first"`, `</users?page=10>; rel="last"`}, res.Header().Values("Link"))
+	assert.Equal(t, "95", res.Header().Get("X-Total-Count"))
+	assert.Equal(t, `{"message":"Page 12 is out of bounds"}`, res.Body.String())
+	readLog(t)
+}
+
+func TestWithHeaderCopies(t *testing.T) {
+	origin := NewHint(416, "").(GError).WithLink("first", "/files/1")
+	copied := origin.WithLink("last", "/files/9")
+	assert.Len(t, origin.Headers.Values("Link"), 1)
+	assert.Len(t, copied.Headers.Values("Link"), 2)
+}
+
+func TestStatusRewriteFunc(t *testing.T) {
+	var logged []int
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		IncludeCode: true,
+		LoggingFunc: func(code int, err error) {
+			logged = append(logged, code)
+		},
+		StatusRewriteFunc: func(code int) int {
+			if code == http.StatusTeapot {
+				return 400
+			}
+			return code
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 418, "I'm a teapot")
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 400, res.Code)
+	assert.Equal(t, `{"code":400,"message":"I'm a teapot"}`, res.Body.String())
+	assert.Equal(t, []int{418}, logged)
+}
+
+func TestIncludeInstance(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	hostname, _ := os.Hostname()
+	for _, option := range []MiddlewareOption{
+		{IncludeInstance: true, InstanceID: "api-7f9c"},
+		{IncludeInstance: true},
+		{InstanceID: "api-7f9c"},
+	} {
+		router := gin.New()
+		router.Use(Middleware(option))
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithErrorAndHint(c, 500, errors.New("failure"), "Internal error")
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, "failure", readLog(t))
+		instance, ok := hook.LastEntry().Data["instance"]
+		switch {
+		case !option.IncludeInstance:
+			assert.Equal(t, `{"message":"Internal error"}`, res.Body.String())
+			assert.False(t, ok)
+		case option.InstanceID == "":
+			assert.Equal(t, `{"message":"Internal error","instance":"`+hostname+`"}`, res.Body.String())
+			assert.Equal(t, hostname, instance)
+		default:
+			assert.Equal(t, `{"message":"Internal error","instance":"api-7f9c"}`, res.Body.String())
+			assert.Equal(t, "api-7f9c", instance)
+		}
+	}
+}
+
+func TestPrettyInDebug(t *testing.T) {
+	for _, production := range []bool{false, true} {
+		for _, problemJSON := range []bool{false, true} {
+			router := gin.New()
+			router.Use(Middleware(MiddlewareOption{PrettyInDebug: true, Production: production, ProblemJSON: problemJSON}))
+			path := getTestPath()
+			router.GET(path, func(c *gin.Context) {
+				AbortWithHint(c, 400, "Invalid email address")
+			})
+			res := performRequest(router, "GET", path)
+			readLog(t)
+			var expected string
+			switch {
+			case problemJSON && production:
+				expected = `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid email address","instance":"` + path + `"}`
+			case problemJSON:
+				expected = "{\n    \"type\": \"about:blank\",\n    \"title\": \"Bad Request\",\n    \"status\": 400,\n    \"detail\": \"Invalid email address\",\n    \"instance\": \"" + path + "\"\n}"
+			case production:
+				expected = `{"message":"Invalid email address"}`
+			default:
+				expected = "{\n    \"message\": \"Invalid email address\"\n}"
+			}
+			assert.Equal(t, expected, res.Body.String())
+		}
+	}
+}
+
+func TestIncludeTimestamp(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		IncludeTimestamp: true,
+		Now: func() time.Time {
+			return time.Date(2021, 5, 20, 13, 14, 0, 0, time.FixedZone("CST", 8*60*60))
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 400, "Invalid email address")
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, `{"message":"Invalid email address","timestamp":"2021-05-20T13:14:00+08:00"}`, res.Body.String())
+	readLog(t)
+}
+
+func TestTimestampFormat(t *testing.T) {
+	for format, expected := range map[string]string{
+		"2006-01-02 15:04:05": `"2021-05-20 13:14:00"`,
+		time.RFC1123:          `"Thu, 20 May 2021 13:14:00 CST"`,
+		TimestampEpochMillis:  `1621487640123`,
+		"Jan _2 15:04:05.000": `"May 20 13:14:00.123"`,
+	} {
+		router := gin.New()
+		router.Use(Middleware(MiddlewareOption{
+			IncludeTimestamp: true,
+			TimestampFormat:  format,
+			Now: func() time.Time {
+				return time.Date(2021, 5, 20, 13, 14, 0, 123000000, time.FixedZone("CST", 8*60*60))
+			},
+		}))
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithHint(c, 400, "Invalid email address")
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, `{"message":"Invalid email address","timestamp":`+expected+`}`, res.Body.String(), format)
+		readLog(t)
+	}
+}
+
+func TestCountErrorFunc(t *testing.T) {
+	type attributes struct {
+		code   int
+		method string
+	}
+	counter := map[attributes]int64{}
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		LoggingFunc: func(code int, err error) {},
+		CountErrorFunc: func(ctx context.Context, code int, method, route string) {
+			counter[attributes{code, method}]++
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 404, "Not found")
+	})
+	router.POST(path, func(c *gin.Context) {
+		AbortWithHint(c, 409, "Conflict")
+	})
+	router.PUT(path, func(c *gin.Context) {
+		c.Status(204)
+	})
+	performRequest(router, "GET", path)
+	performRequest(router, "GET", path)
+	performRequest(router, "POST", path)
+	performRequest(router, "PUT", path)
+	assert.Equal(t, map[attributes]int64{
+		{404, "GET"}:  2,
+		{409, "POST"}: 1,
+	}, counter)
+}
+
+func TestTopLevelArray(t *testing.T) {
+	for _, bare := range []bool{false, true} {
+		router := gin.New()
+		router.Use(Middleware(MiddlewareOption{IncludeCode: true, TopLevelArray: bare}))
+		multiPath := getTestPath()
+		router.GET(multiPath, func(c *gin.Context) {
+			merr := &fakeMultiError{Errors: []error{errors.New("name is required"), errors.New("email is invalid")}}
+			AbortWithError(c, 400, NewFromMultiError(422, merr))
+		})
+		singlePath := getTestPath()
+		router.GET(singlePath, func(c *gin.Context) {
+			AbortWithHint(c, 404, "User not found")
+		})
+
+		multi := performRequest(router, "GET", multiPath)
+		readLog(t)
+		single := performRequest(router, "GET", singlePath)
+		readLog(t)
+		var multiBody, singleBody interface{}
+		assert.NoError(t, json.Unmarshal(multi.Body.Bytes(), &multiBody))
+		assert.NoError(t, json.Unmarshal(single.Body.Bytes(), &singleBody))
+		if bare {
+			assert.IsType(t, []interface{}{}, multiBody)
+			assert.IsType(t, []interface{}{}, singleBody)
+			assert.Equal(t, `[{"code":422,"message":"name is required"},{"code":422,"message":"email is invalid"}]`, multi.Body.String())
+			assert.Equal(t, `[{"code":404,"message":"User not found"}]`, single.Body.String())
+		} else {
+			assert.IsType(t, map[string]interface{}{}, multiBody)
+			assert.IsType(t, map[string]interface{}{}, singleBody)
+		}
+	}
+}
+
+func TestTopLevelArraySharedMembers(t *testing.T) {
+	merr := &fakeMultiError{}
+	for i := 0; i < 5; i++ {
+		merr.Errors = append(merr.Errors, fmt.Errorf("row %d is invalid", i))
+	}
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		TopLevelArray:     true,
+		MaxBodyErrors:     2,
+		GenerateRequestID: true,
+		IDGenerator: func() string {
+			return "req-1"
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		err := NewFromMultiError(422, merr).(GError).WithFieldError("rows", "invalid", "Rows are invalid")
+		AbortWithError(c, 422, err)
+	})
+
+	res := performRequest(router, "GET", path)
+	readLog(t)
+	assert.Equal(t, 422, res.Code)
+	assert.Equal(t, `[`+
+		`{"message":"row 0 is invalid","fields":[{"field":"rows","code":"invalid","message":"Rows are invalid"}],"request_id":"req-1"},`+
+		`{"message":"row 1 is invalid","fields":[{"field":"rows","code":"invalid","message":"Rows are invalid"}],"request_id":"req-1"}`+
+		`]`, res.Body.String())
+}
+
+func TestMaxChainDepth(t *testing.T) {
+	err := errors.New("level 0")
+	for i := 1; i < 10; i++ {
+		err = fmt.Errorf("level %d: %w", i, err)
+	}
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		MaxChainDepth: 3,
+		DetailVisibilityFunc: func(c *gin.Context) bool {
+			return true
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithErrorAndHint(c, 500, err, "Internal error")
+	})
+	res := performRequest(router, "GET", path)
+	readLog(t)
+	var body struct {
+		Chain []string `json:"chain"`
+	}
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+	assert.Equal(t, []string{
+		err.Error(),
+		errors.Unwrap(err).Error(),
+		errors.Unwrap(errors.Unwrap(err)).Error(),
+	}, body.Chain)
+	assert.True(t, strings.HasPrefix(body.Chain[2], "level 7: level 6"))
+}
+
+func TestExposePublicError(t *testing.T) {
+	for _, expose := range []bool{false, true} {
+		var logged []string
+		router := gin.New()
+		// A logging middleware like gin-contrib/zap reading c.Errors.
+		router.Use(func(c *gin.Context) {
+			c.Next()
+			for _, err := range c.Errors.ByType(gin.ErrorTypePublic) {
+				logged = append(logged, err.Error())
+			}
+		})
+		router.Use(Middleware(MiddlewareOption{Production: true, ExposePublicError: expose}))
+		hintPath := getTestPath()
+		router.GET(hintPath, func(c *gin.Context) {
+			AbortWithErrorAndHint(c, 409, errors.New("duplicate key"), "User already exists")
+		})
+		maskedPath := getTestPath()
+		router.GET(maskedPath, func(c *gin.Context) {
+			_ = c.AbortWithError(500, errors.New("sql: connection refused"))
+		})
+		performRequest(router, "GET", hintPath)
+		readLog(t)
+		performRequest(router, "GET", maskedPath)
+		readLog(t)
+		if expose {
+			assert.Equal(t, []string{"User already exists", "Internal Server Error"}, logged)
+		} else {
+			assert.Empty(t, logged)
+		}
+	}
+}
+
+func TestAbortWithoutHintKeepsWrittenBody(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		IncludeCode:  true,
+		DefaultHints: map[int]string{400: "Bad request"},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		c.JSON(400, gin.H{"field": "email", "reason": "invalid"})
+		AbortWithHint(c, 400, "")
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 400, res.Code)
+	assert.Equal(t, `{"field":"email","reason":"invalid"}`, res.Body.String())
+	readLog(t)
+}
+
+func TestUnmarshalableResponseBody(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		LoggingFunc: func(code int, err error) {},
+		ResponseBodyFunc: func(code int, message string) interface{} {
+			return gin.H{"message": message, "done": make(chan struct{})}
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 400, "Invalid email address")
+	})
+
+	assert.NotPanics(t, func() {
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, 400, res.Code)
+		assert.Empty(t, res.Body.String())
+	})
+	assert.Equal(t, "json: unsupported type: chan struct {}", readLog(t))
+	assert.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+}
+
+func TestLogContextKeys(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", 42)
+		c.Set("tenant", "acme")
+		c.Set("session", "secret")
+	})
+	router.Use(Middleware(MiddlewareOption{LogContextKeys: []string{"user_id", "tenant", "region"}}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithError(c, 500, errors.New("sql: connection refused"))
+	})
+
+	performRequest(router, "GET", path)
+	assert.Equal(t, "sql: connection refused", readLog(t))
+	assert.Equal(t, logrus.Fields{"user_id": 42, "tenant": "acme"}, hook.LastEntry().Data)
+}
+
+func TestPublicHintsOnly(t *testing.T) {
+	for _, production := range []bool{false, true} {
+		router := gin.New()
+		router.Use(Middleware(MiddlewareOption{
+			Production:      production,
+			PublicHintsOnly: true,
+			DefaultHints:    map[int]string{502: "Upstream unavailable"},
+		}))
+		internalPath := getTestPath()
+		router.GET(internalPath, func(c *gin.Context) {
+			AbortWithErrorAndHint(c, 409, errors.New("duplicate key"), "Row 42 of table users already exists")
+		})
+		publicPath := getTestPath()
+		router.GET(publicPath, func(c *gin.Context) {
+			AbortWithError(c, 409, New(409, errors.New("duplicate key"), "User already exists").(GError).WithPublic())
+		})
+		defaultHintPath := getTestPath()
+		router.GET(defaultHintPath, func(c *gin.Context) {
+			AbortWithErrorAndHint(c, 502, errors.New("dial tcp: connection refused"), "billing.internal:8080 is down")
+		})
+
+		res := performRequest(router, "GET", internalPath)
+		readLog(t)
+		assert.Equal(t, 409, res.Code)
+		if production {
+			assert.Equal(t, `{"message":"Conflict"}`, res.Body.String())
+		} else {
+			assert.Equal(t, `{"message":"Row 42 of table users already exists"}`, res.Body.String())
+		}
+		res = performRequest(router, "GET", publicPath)
+		readLog(t)
+		assert.Equal(t, `{"message":"User already exists"}`, res.Body.String())
+		res = performRequest(router, "GET", defaultHintPath)
+		readLog(t)
+		if production {
+			assert.Equal(t, `{"message":"Upstream unavailable"}`, res.Body.String())
+		} else {
+			assert.Equal(t, `{"message":"billing.internal:8080 is down"}`, res.Body.String())
+		}
+	}
+}
+
+func TestWithPublic(t *testing.T) {
+	origin := NewHint(400, "Invalid email address").(GError)
+	public := origin.WithPublic()
+	assert.False(t, origin.Public)
+	assert.True(t, public.Public)
+	assert.Equal(t, origin.Hint, public.Hint)
+}
+
+func TestAlwaysBodyForServerErrors(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{AlwaysBodyForServerErrors: true, IncludeCode: true}))
+	serverErrorPath := getTestPath()
+	router.GET(serverErrorPath, func(c *gin.Context) {
+		AbortWithError(c, 500, errors.New("sql: connection refused"))
+	})
+	hintPath := getTestPath()
+	router.GET(hintPath, func(c *gin.Context) {
+		AbortWithErrorAndHint(c, 503, errors.New("sql: connection refused"), "Down for maintenance")
+	})
+	clientErrorPath := getTestPath()
+	router.GET(clientErrorPath, func(c *gin.Context) {
+		AbortWithError(c, 400, errors.New("bad input"))
+	})
+
+	res := performRequest(router, "GET", serverErrorPath)
+	assert.Equal(t, 500, res.Code)
+	assert.Equal(t, `{"code":500,"message":"Internal Server Error"}`, res.Body.String())
+	assert.Equal(t, "sql: connection refused", readLog(t))
+	res = performRequest(router, "GET", hintPath)
+	assert.Equal(t, `{"code":503,"message":"Down for maintenance"}`, res.Body.String())
+	readLog(t)
+	res = performRequest(router, "GET", clientErrorPath)
+	assert.Equal(t, 400, res.Code)
+	assert.Empty(t, res.Body.String())
+	assert.Equal(t, "bad input", readLog(t))
+}
+
+func TestFramePackage(t *testing.T) {
+	assert.Equal(t, "github.com/gin-gonic/gin", framePackage("github.com/gin-gonic/gin.(*Context).Next"))
+	assert.Equal(t, "runtime", framePackage("runtime.goexit"))
+	assert.Equal(t, "net/http", framePackage("net/http.HandlerFunc.ServeHTTP"))
+}
+
+func TestIncludeErrorType(t *testing.T) {
+	opError := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	for _, production := range []bool{false, true} {
+		router := gin.New()
+		router.Use(Middleware(MiddlewareOption{IncludeErrorType: true, Production: production}))
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			AbortWithErrorAndHint(c, 502, opError, "Upstream unavailable")
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, 502, res.Code)
+		if production {
+			assert.Equal(t, `{"message":"Upstream unavailable"}`, res.Body.String())
+		} else {
+			assert.Equal(t, `{"message":"Upstream unavailable","error_type":"*net.OpError"}`, res.Body.String())
+		}
+		assert.Equal(t, "dial tcp: connection refused", readLog(t))
+	}
+}
+
+func TestProduction(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{Production: true}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		_ = c.AbortWithError(500, errors.New("sql: connection refused"))
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, res.Code, 500)
+	assert.Equal(t, "sql: connection refused", readLog(t))
+	assert.Equal(t, `{"message":"Internal Server Error"}`, res.Body.String())
+}
+
+func TestQuietCodes(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	logrus.SetLevel(logrus.DebugLevel)
+	defer logrus.SetLevel(logrus.InfoLevel)
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{QuietCodes: []int{401}}))
+	unauthorizedPath := getTestPath()
+	router.GET(unauthorizedPath, func(c *gin.Context) {
+		AbortWithError(c, 401, errors.New("token expired"))
+	})
+	badRequestPath := getTestPath()
+	router.GET(badRequestPath, func(c *gin.Context) {
+		AbortWithError(c, 400, errors.New("bad input"))
+	})
+
+	performRequest(router, "GET", unauthorizedPath)
+	assert.Equal(t, "token expired", readLog(t))
+	assert.Equal(t, logrus.DebugLevel, hook.LastEntry().Level)
+
+	performRequest(router, "GET", badRequestPath)
+	assert.Equal(t, "bad input", readLog(t))
+	assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+}
+
+type quotaViolation struct {
+	Subject     string `json:"subject"`
+	Description string `json:"description"`
+}
+
+func TestWithDetail(t *testing.T) {
+	origin := NewHint(429, "quota exceeded").(GError)
+	gErr := origin.
+		WithDetail(quotaViolation{Subject: "project:42", Description: "daily limit"}).
+		WithDetail(gin.H{"retryDelay": "60s"})
+	assert.Empty(t, origin.Details)
+	assert.Len(t, gErr.Details, 2)
+
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithError(c, 500, gErr)
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, res.Code, 429)
+	assert.Equal(t, `{"message":"quota exceeded","details":[{"subject":"project:42","description":"daily limit"},{"retryDelay":"60s"}]}`, res.Body.String())
+}
+
+func TestErrorTypeCodes(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	t.Run("bind error", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			_ = c.Error(errors.New("invalid body")).SetType(gin.ErrorTypeBind)
+			c.Abort()
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 400)
+		assert.Equal(t, "invalid body", readLog(t))
+		body := parseBody(t, res)
+		assert.Equal(t, "invalid body", body["message"])
+	})
+	t.Run("explicit code", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			_ = c.AbortWithError(422, errors.New("invalid body")).SetType(gin.ErrorTypeBind)
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 422)
+		assert.Equal(t, "invalid body", readLog(t))
+	})
+}
+
+func TestWithFieldError(t *testing.T) {
+	origin := NewHint(422, "Invalid user").(GError)
+	gErr := origin.
+		WithFieldError("email", "required", "Email is required").
+		WithFieldError("age", "min", "Age must be at least 18")
+	assert.Empty(t, origin.Fields)
+	assert.Len(t, gErr.Fields, 2)
+
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithError(c, 500, gErr)
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 422, res.Code)
+	assert.Equal(t, `{"message":"Invalid user","fields":[{"field":"email","code":"required","message":"Email is required"},{"field":"age","code":"min","message":"Age must be at least 18"}]}`, res.Body.String())
+	readLog(t)
+}
@@ -0,0 +1,93 @@
+// Package gerrortest provides helpers to test handlers using gerror.
+package gerrortest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/dcalsky/gerror"
+	"github.com/gin-gonic/gin"
+)
+
+// TestingT is the subset of *testing.T used by the helpers.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertNoLeak fails the test if the response body contains any of the
+// sensitive substrings, e.g. "sql:" or file paths.
+func AssertNoLeak(t TestingT, recorder *httptest.ResponseRecorder, sensitiveSubstrings ...string) bool {
+	t.Helper()
+	body := recorder.Body.String()
+	ok := true
+	for _, sensitive := range sensitiveSubstrings {
+		if strings.Contains(body, sensitive) {
+			t.Errorf("response body leaks %q: %s", sensitive, body)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// InvokeHandler runs the handler with the request in a minimal gin context,
+// without a router, and returns the error it aborted with. The abort helpers
+// don't warn about the missing middleware.
+func InvokeHandler(handler gin.HandlerFunc, req *http.Request) (gerror.GError, bool) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = req
+	gerror.WithoutMiddleware(c)
+	handler(c)
+	lastError := c.Errors.Last()
+	if !c.IsAborted() || lastError == nil {
+		return gerror.GError{}, false
+	}
+	if gError, ok := lastError.Err.(gerror.GError); ok {
+		return gError, true
+	}
+	return gerror.GError{
+		Code: c.Writer.Status(),
+		Err:  lastError.Err,
+		Hint: lastError.Error(),
+	}, true
+}
+
+// WithGinMode runs f with gin set to the mode, e.g. gin.TestMode, and restores
+// the previous mode afterwards. The mode is global, so tests using it must not
+// run in parallel.
+func WithGinMode(mode string, f func()) {
+	previous := gin.Mode()
+	gin.SetMode(mode)
+	defer gin.SetMode(previous)
+	f()
+}
+
+// LoggedError is an error logged by the middleware with its status code.
+type LoggedError struct {
+	Code int
+	Err  error
+}
+
+// LoggingRecorder records the errors logged by the middleware, to assert the
+// logging without hooking logrus. Pass its LoggingFunc method as
+// MiddlewareOption.LoggingFunc.
+type LoggingRecorder struct {
+	mu      sync.Mutex
+	entries []LoggedError
+}
+
+// LoggingFunc records the code and the error.
+func (r *LoggingRecorder) LoggingFunc(code int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, LoggedError{Code: code, Err: err})
+}
+
+// Entries returns the errors recorded so far, in the order they were logged.
+func (r *LoggingRecorder) Entries() []LoggedError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]LoggedError(nil), r.entries...)
+}
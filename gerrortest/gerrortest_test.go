@@ -0,0 +1,153 @@
+package gerrortest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dcalsky/gerror"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func performRequest(r http.Handler, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAssertNoLeak(t *testing.T) {
+	router := gin.New()
+	router.Use(gerror.Middleware(gerror.MiddlewareOption{
+		LoggingFunc: func(code int, err error) {},
+	}))
+	router.GET("/leak", func(c *gin.Context) {
+		_ = c.AbortWithError(500, errors.New("sql: no rows in result set"))
+	})
+	router.GET("/masked", func(c *gin.Context) {
+		gerror.AbortWithErrorAndHint(c, 500, errors.New("sql: no rows in result set"), "Internal error")
+	})
+
+	t.Run("leak", func(t *testing.T) {
+		ft := &fakeT{}
+		res := performRequest(router, "/leak")
+		assert.False(t, AssertNoLeak(ft, res, "sql:", "/var/lib"))
+		assert.Len(t, ft.errors, 1)
+		assert.Contains(t, ft.errors[0], `"sql:"`)
+	})
+	t.Run("masked", func(t *testing.T) {
+		ft := &fakeT{}
+		res := performRequest(router, "/masked")
+		assert.True(t, AssertNoLeak(ft, res, "sql:", "/var/lib"))
+		assert.Empty(t, ft.errors)
+	})
+}
+
+func TestInvokeHandler(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	req, _ := http.NewRequest("GET", "/users/42", nil)
+	t.Run("aborted", func(t *testing.T) {
+		gErr, ok := InvokeHandler(func(c *gin.Context) {
+			gerror.AbortWithHint(c, 404, "user not found")
+		}, req)
+		assert.True(t, ok)
+		assert.Equal(t, 404, gErr.Code)
+		assert.Equal(t, "user not found", gErr.Hint)
+	})
+	t.Run("aborted with gin error", func(t *testing.T) {
+		gErr, ok := InvokeHandler(func(c *gin.Context) {
+			_ = c.AbortWithError(503, errors.New("unavailable"))
+		}, req)
+		assert.True(t, ok)
+		assert.Equal(t, 503, gErr.Code)
+		assert.EqualError(t, gErr.Err, "unavailable")
+	})
+	t.Run("not aborted", func(t *testing.T) {
+		_, ok := InvokeHandler(func(c *gin.Context) {
+			c.JSON(200, gin.H{"id": 42})
+		}, req)
+		assert.False(t, ok)
+	})
+	assert.Empty(t, hook.AllEntries())
+}
+
+func TestWithGinMode(t *testing.T) {
+	router := gin.New()
+	router.Use(gerror.Middleware(gerror.MiddlewareOption{
+		IncludeCode: true,
+		LoggingFunc: func(code int, err error) {},
+	}))
+	router.GET("/hint", func(c *gin.Context) {
+		gerror.AbortWithHint(c, 404, "user not found")
+	})
+	router.GET("/gin", func(c *gin.Context) {
+		_ = c.AbortWithError(503, errors.New("unavailable"))
+	})
+	router.GET("/mismatch", func(c *gin.Context) {
+		c.Status(400)
+		gerror.AbortWithHint(c, 422, "invalid user")
+	})
+
+	previous := gin.Mode()
+	var outputs []string
+	for _, mode := range []string{gin.DebugMode, gin.ReleaseMode, gin.TestMode} {
+		WithGinMode(mode, func() {
+			assert.Equal(t, mode, gin.Mode())
+			var output string
+			for _, path := range []string{"/hint", "/gin", "/mismatch"} {
+				res := performRequest(router, path)
+				output += fmt.Sprintf("%d %s %s\n", res.Code, res.Header().Get("Content-Type"), res.Body.String())
+			}
+			outputs = append(outputs, output)
+		})
+		assert.Equal(t, previous, gin.Mode())
+	}
+	assert.Equal(t, "404 application/json; charset=utf-8 {\"code\":404,\"message\":\"user not found\"}\n"+
+		"503 application/json; charset=utf-8 {\"code\":503,\"message\":\"unavailable\"}\n"+
+		"422 application/json; charset=utf-8 {\"code\":422,\"message\":\"invalid user\"}\n", outputs[0])
+	assert.Equal(t, outputs[0], outputs[1])
+	assert.Equal(t, outputs[0], outputs[2])
+}
+
+func TestLoggingRecorder(t *testing.T) {
+	recorder := &LoggingRecorder{}
+	router := gin.New()
+	router.Use(gerror.Middleware(gerror.MiddlewareOption{
+		LoggingFunc: recorder.LoggingFunc,
+	}))
+	router.GET("/users/:id", func(c *gin.Context) {
+		gerror.AbortWithErrorAndHint(c, 404, errors.New("sql: no rows in result set"), "user not found")
+	})
+	router.GET("/gin", func(c *gin.Context) {
+		_ = c.AbortWithError(503, errors.New("unavailable"))
+	})
+	router.GET("/ok", func(c *gin.Context) {
+		c.JSON(200, gin.H{"id": 42})
+	})
+
+	assert.Empty(t, recorder.Entries())
+	performRequest(router, "/users/42")
+	performRequest(router, "/ok")
+	performRequest(router, "/gin")
+	entries := recorder.Entries()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, 404, entries[0].Code)
+		assert.EqualError(t, entries[0].Err, "sql: no rows in result set")
+		assert.Equal(t, 503, entries[1].Code)
+		assert.EqualError(t, entries[1].Err, "unavailable")
+	}
+}
@@ -0,0 +1,55 @@
+package gerror
+
+// gRPC status codes, see google.golang.org/grpc/codes.
+const (
+	grpcOK                 = 0
+	grpcCanceled           = 1
+	grpcUnknown            = 2
+	grpcInvalidArgument    = 3
+	grpcDeadlineExceeded   = 4
+	grpcNotFound           = 5
+	grpcAlreadyExists      = 6
+	grpcPermissionDenied   = 7
+	grpcResourceExhausted  = 8
+	grpcFailedPrecondition = 9
+	grpcOutOfRange         = 11
+	grpcUnimplemented      = 12
+	grpcInternal           = 13
+	grpcUnavailable        = 14
+	grpcUnauthenticated    = 16
+)
+
+var grpcCodes = map[int]uint32{
+	400: grpcInvalidArgument,
+	401: grpcUnauthenticated,
+	403: grpcPermissionDenied,
+	404: grpcNotFound,
+	409: grpcAlreadyExists,
+	412: grpcFailedPrecondition,
+	416: grpcOutOfRange,
+	429: grpcResourceExhausted,
+	499: grpcCanceled,
+	500: grpcInternal,
+	501: grpcUnimplemented,
+	503: grpcUnavailable,
+	504: grpcDeadlineExceeded,
+}
+
+// GRPCCode maps the status code of the error to a gRPC status code, so
+// services exposing both HTTP and gRPC can return the same error. The
+// grpcstatus module converts the whole error, with its details.
+//
+// Other 4xx codes map to FailedPrecondition, 2xx codes to OK and the rest to
+// Unknown.
+func (g GError) GRPCCode() uint32 {
+	if code, ok := grpcCodes[g.Code]; ok {
+		return code
+	}
+	switch {
+	case g.Code >= 200 && g.Code < 300:
+		return grpcOK
+	case g.Code >= 400 && g.Code < 500:
+		return grpcFailedPrecondition
+	}
+	return grpcUnknown
+}
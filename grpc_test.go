@@ -0,0 +1,25 @@
+package gerror
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGRPCCode(t *testing.T) {
+	for code, expected := range map[int]uint32{
+		200: 0,
+		400: 3,
+		401: 16,
+		403: 7,
+		404: 5,
+		409: 6,
+		422: 9,
+		429: 8,
+		500: 13,
+		502: 2,
+		503: 14,
+		504: 4,
+	} {
+		assert.Equal(t, expected, NewHint(code, "hint").(GError).GRPCCode(), code)
+	}
+}
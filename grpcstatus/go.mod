@@ -0,0 +1,13 @@
+module github.com/dcalsky/gerror/grpcstatus
+
+go 1.18
+
+require (
+	github.com/dcalsky/gerror v0.0.0
+	github.com/stretchr/testify v1.7.0
+	google.golang.org/genproto v0.0.0-20230306155012-7f2fa6fef1f4
+	google.golang.org/grpc v1.55.0
+	google.golang.org/protobuf v1.30.0
+)
+
+replace github.com/dcalsky/gerror => ../
@@ -0,0 +1,76 @@
+// Package grpcstatus converts gerror errors to gRPC statuses, for services
+// exposing both HTTP and gRPC.
+//
+// It is a module of its own, so gerror doesn't depend on gRPC.
+package grpcstatus
+
+import (
+	"encoding/json"
+
+	"github.com/dcalsky/gerror"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ToGRPCStatus converts the error to a gRPC status with the code from
+// GError.GRPCCode and the hint as message.
+//
+// The field errors are attached as a BadRequest detail, followed by the
+// details of the error: proto messages as they are and other values as
+// structpb.Value. Details that can't be converted are left out, and so are
+// all of them for the OK code.
+func ToGRPCStatus(g gerror.GError) *status.Status {
+	st := status.New(codes.Code(g.GRPCCode()), g.Hint)
+	details := detailMessages(g)
+	if len(details) == 0 {
+		return st
+	}
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+func detailMessages(g gerror.GError) []protoiface.MessageV1 {
+	var messages []protoiface.MessageV1
+	if len(g.Fields) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, field := range g.Fields {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field,
+				Description: field.Message,
+			})
+		}
+		messages = append(messages, badRequest)
+	}
+	for _, detail := range g.Details {
+		if message, ok := detail.(protoiface.MessageV1); ok {
+			messages = append(messages, message)
+		} else if value, ok := structValue(detail); ok {
+			messages = append(messages, value)
+		}
+	}
+	return messages
+}
+
+// structValue converts a detail to a structpb.Value, going through JSON for
+// the types structpb.NewValue doesn't know, like structs.
+func structValue(detail interface{}) (*structpb.Value, bool) {
+	if value, err := structpb.NewValue(detail); err == nil {
+		return value, true
+	}
+	data, err := json.Marshal(detail)
+	if err != nil {
+		return nil, false
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, false
+	}
+	value, err := structpb.NewValue(decoded)
+	return value, err == nil
+}
@@ -0,0 +1,58 @@
+package grpcstatus
+
+import (
+	"github.com/dcalsky/gerror"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/structpb"
+	"testing"
+)
+
+func TestToGRPCStatus(t *testing.T) {
+	for code, expected := range map[int]codes.Code{
+		400: codes.InvalidArgument,
+		401: codes.Unauthenticated,
+		404: codes.NotFound,
+		409: codes.AlreadyExists,
+		422: codes.FailedPrecondition,
+		429: codes.ResourceExhausted,
+		500: codes.Internal,
+		503: codes.Unavailable,
+	} {
+		st := ToGRPCStatus(gerror.NewHint(code, "hint").(gerror.GError))
+		assert.Equal(t, expected, st.Code(), code)
+		assert.Equal(t, "hint", st.Message(), code)
+		assert.Empty(t, st.Details(), code)
+	}
+}
+
+func TestToGRPCStatusDetails(t *testing.T) {
+	type quota struct {
+		Limit int `json:"limit"`
+	}
+	g := gerror.NewHint(400, "Invalid user").(gerror.GError).
+		WithFieldError("email", "invalid", "Email is invalid").
+		WithDetail(&errdetails.ErrorInfo{Reason: "INVALID_EMAIL", Domain: "users.example.com"}).
+		WithDetail(map[string]interface{}{"retry": false}).
+		WithDetail(quota{Limit: 10}).
+		WithDetail(make(chan int))
+
+	st := ToGRPCStatus(g)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	details := st.Details()
+	if assert.Len(t, details, 4) {
+		badRequest := details[0].(*errdetails.BadRequest)
+		assert.Equal(t, "email", badRequest.FieldViolations[0].Field)
+		assert.Equal(t, "Email is invalid", badRequest.FieldViolations[0].Description)
+		assert.Equal(t, "INVALID_EMAIL", details[1].(*errdetails.ErrorInfo).Reason)
+		assert.Equal(t, map[string]interface{}{"retry": false}, details[2].(*structpb.Value).AsInterface())
+		assert.Equal(t, map[string]interface{}{"limit": float64(10)}, details[3].(*structpb.Value).AsInterface())
+	}
+}
+
+func TestToGRPCStatusOK(t *testing.T) {
+	st := ToGRPCStatus(gerror.NewHint(200, "Done").(gerror.GError).WithDetail("ignored"))
+	assert.Equal(t, codes.OK, st.Code())
+	assert.Empty(t, st.Details())
+}
@@ -0,0 +1,26 @@
+package gerror
+
+import "github.com/gin-gonic/gin"
+
+// HALLink is a link of a HAL document.
+type HALLink struct {
+	Href string `json:"href"`
+}
+
+// HALError is the HAL+JSON document written when MiddlewareOption.HALJSON is
+// set. It holds the members of the default response body next to the links.
+type HALError struct {
+	Links map[string]HALLink `json:"_links"`
+	ErrorResponse
+}
+
+func (h HALError) MarshalJSON() ([]byte, error) {
+	return marshalFields(append([]jsonField{{"_links", h.Links}}, h.ErrorResponse.jsonFields()...))
+}
+
+func newHALError(c *gin.Context, body ErrorResponse) HALError {
+	return HALError{
+		Links:         map[string]HALLink{"self": {Href: c.Request.URL.Path}},
+		ErrorResponse: body,
+	}
+}
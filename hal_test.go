@@ -0,0 +1,85 @@
+package gerror
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHALJSON(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{HALJSON: true, IncludeCode: true}))
+	router.GET("/users/:id", func(c *gin.Context) {
+		AbortWithErrorAndHint(c, 404, errors.New("no rows"), "User not found")
+	})
+
+	res := performRequest(router, "GET", "/users/42?expand=orders")
+	readLog(t)
+	assert.Equal(t, 404, res.Code)
+	assert.Equal(t, "application/hal+json", res.Header().Get("Content-Type"))
+	assert.Equal(t, `{"_links":{"self":{"href":"/users/42"}},"code":404,"message":"User not found"}`, res.Body.String())
+	var body HALError
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+	assert.Equal(t, "/users/42", body.Links["self"].Href)
+}
+
+func TestHALJSONWithoutCode(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{HALJSON: true}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 400, "Invalid email address")
+	})
+
+	res := performRequest(router, "GET", path)
+	readLog(t)
+	assert.Equal(t, 400, res.Code)
+	assert.Equal(t, `{"_links":{"self":{"href":"`+path+`"}},"message":"Invalid email address"}`, res.Body.String())
+}
+
+func TestHALJSONWithoutMessage(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{HALJSON: true}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithError(c, 400, errors.New("bad input"))
+	})
+
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, "bad input", readLog(t))
+	assert.Equal(t, 400, res.Code)
+	assert.Empty(t, res.Body.String())
+}
+
+func TestHALJSONSharedMembers(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		HALJSON:           true,
+		GenerateRequestID: true,
+		IDGenerator: func() string {
+			return "req-1"
+		},
+		IncludeInstance:  true,
+		InstanceID:       "api-1",
+		IncludeTimestamp: true,
+		TimestampFormat:  TimestampEpochMillis,
+		Now: func() time.Time {
+			return time.UnixMilli(1621487640000)
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		err := New(422, errors.New("invalid email"), "Validation failed").(GError).WithFieldError("email", "invalid", "Email is invalid")
+		AbortWithError(c, 422, err)
+	})
+
+	res := performRequest(router, "GET", path)
+	readLog(t)
+	assert.Equal(t, 422, res.Code)
+	assert.Equal(t, `{"_links":{"self":{"href":"`+path+`"}},"message":"Validation failed",`+
+		`"fields":[{"field":"email","code":"invalid","message":"Email is invalid"}],`+
+		`"instance":"api-1","request_id":"req-1","timestamp":1621487640000}`, res.Body.String())
+}
@@ -0,0 +1,27 @@
+package gerror
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Handle adapts a service-style handler to a gin.HandlerFunc. The returned data
+// is written as JSON with status 200, a GError aborts with its own code and any
+// other error aborts with 500.
+func Handle[T any](fn func(c *gin.Context) (T, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := fn(c)
+		if err != nil {
+			// fn has already returned, so it is no longer on the stack.
+			gError, ok := err.(GError)
+			if !ok {
+				gError = New(500, err, "").(GError)
+			}
+			if c.GetBool(captureCallerKey) {
+				gError.Caller = funcName(fn)
+			}
+			AbortWithError(c, 500, gError)
+			return
+		}
+		c.JSON(200, data)
+	}
+}
@@ -0,0 +1,46 @@
+package gerror
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type handleUser struct {
+	Name string `json:"name"`
+}
+
+func TestHandle(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	t.Run("success", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, Handle(func(c *gin.Context) (handleUser, error) {
+			return handleUser{Name: "foo"}, nil
+		}))
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 200)
+		body := parseBody(t, res)
+		assert.Equal(t, "foo", body["name"])
+	})
+	t.Run("gerror", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, Handle(func(c *gin.Context) (*handleUser, error) {
+			return nil, NewHint(404, "user not found")
+		}))
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 404)
+		body := parseBody(t, res)
+		assert.Equal(t, "user not found", body["message"])
+	})
+	t.Run("other error", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, Handle(func(c *gin.Context) (*handleUser, error) {
+			return nil, errors.New("handle error")
+		}))
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 500)
+		assert.Equal(t, "handle error", readLog(t))
+	})
+}
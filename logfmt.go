@@ -0,0 +1,50 @@
+package gerror
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LogfmtLoggingFunc returns a LoggingFuncWithContext writing one logfmt line
+// per error to w, like:
+//
+//	level=error code=500 method=GET path=/users error="sql: connection refused"
+//
+// 5xx are logged at error level, the other codes at warning level.
+func LogfmtLoggingFunc(w io.Writer) func(c *gin.Context, code int, err error) {
+	var mu sync.Mutex
+	return func(c *gin.Context, code int, err error) {
+		level := "warning"
+		if code >= 500 {
+			level = "error"
+		}
+		var buf bytes.Buffer
+		writeLogfmt(&buf, "level", level)
+		writeLogfmt(&buf, "code", strconv.Itoa(code))
+		writeLogfmt(&buf, "method", c.Request.Method)
+		writeLogfmt(&buf, "path", RoutePath(c))
+		writeLogfmt(&buf, "error", err.Error())
+		buf.WriteByte('\n')
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = w.Write(buf.Bytes())
+	}
+}
+
+func writeLogfmt(buf *bytes.Buffer, key, value string) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(key)
+	buf.WriteByte('=')
+	if value == "" || strings.ContainsAny(value, " =\"\\") || strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		value = strconv.Quote(value)
+	}
+	buf.WriteString(value)
+}
@@ -0,0 +1,68 @@
+package gerror
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func parseLogfmt(t *testing.T, line string) map[string]string {
+	pairs := map[string]string{}
+	for line != "" {
+		eq := strings.IndexByte(line, '=')
+		if !assert.True(t, eq > 0, line) {
+			return pairs
+		}
+		key, rest := line[:eq], line[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			assert.NoError(t, err)
+			value, _ = strconv.Unquote(quoted)
+			rest = rest[len(quoted):]
+		} else if end := strings.IndexByte(rest, ' '); end >= 0 {
+			value, rest = rest[:end], rest[end:]
+		} else {
+			value, rest = rest, ""
+		}
+		pairs[key] = value
+		line = strings.TrimPrefix(rest, " ")
+	}
+	return pairs
+}
+
+func TestLogfmtLoggingFunc(t *testing.T) {
+	var out bytes.Buffer
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{LoggingFuncWithContext: LogfmtLoggingFunc(&out)}))
+	router.GET("/users/:id", func(c *gin.Context) {
+		AbortWithError(c, 500, errors.New(`sql: column "name" = NULL`))
+	})
+	router.POST("/users", func(c *gin.Context) {
+		AbortWithError(c, 400, errors.New("invalid"))
+	})
+
+	performRequest(router, "GET", "/users/42")
+	performRequest(router, "POST", "/users")
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, `level=error code=500 method=GET path=/users/42 error="sql: column \"name\" = NULL"`, lines[0])
+	assert.Equal(t, map[string]string{
+		"level":  "error",
+		"code":   "500",
+		"method": "GET",
+		"path":   "/users/42",
+		"error":  `sql: column "name" = NULL`,
+	}, parseLogfmt(t, lines[0]))
+	assert.Equal(t, map[string]string{
+		"level":  "warning",
+		"code":   "400",
+		"method": "POST",
+		"path":   "/users",
+		"error":  "invalid",
+	}, parseLogfmt(t, lines[1]))
+}
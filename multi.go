@@ -0,0 +1,48 @@
+package gerror
+
+import (
+	"errors"
+)
+
+// NewFromMultiError flattens a multi error, like *multierror.Error of
+// hashicorp/go-multierror or the result of errors.Join, into a GError whose
+// response body lists each underlying error.
+func NewFromMultiError(code int, merr error) error {
+	return GError{
+		Code:   code,
+		Err:    merr,
+		Errors: flattenErrors(merr),
+	}
+}
+
+func flattenErrors(err error) []error {
+	var wrapped []error
+	switch multi := outermostMultiError(err).(type) {
+	case interface{ WrappedErrors() []error }:
+		wrapped = multi.WrappedErrors()
+	case interface{ Unwrap() []error }:
+		wrapped = multi.Unwrap()
+	default:
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range wrapped {
+		if e != nil {
+			errs = append(errs, flattenErrors(e)...)
+		}
+	}
+	return errs
+}
+
+// outermostMultiError returns the first multi error found by unwrapping
+// single-error wrappers like fmt.Errorf("...: %w", merr), or nil. The members
+// of a multi error are not searched, so their siblings are not lost.
+func outermostMultiError(err error) error {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch err.(type) {
+		case interface{ WrappedErrors() []error }, interface{ Unwrap() []error }:
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package gerror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type fakeMultiError struct {
+	Errors []error
+}
+
+func (e *fakeMultiError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *fakeMultiError) WrappedErrors() []error {
+	return e.Errors
+}
+
+type fakeJoinError struct {
+	errs []error
+}
+
+func (e fakeJoinError) Error() string {
+	return "joined error"
+}
+
+func (e fakeJoinError) Unwrap() []error {
+	return e.errs
+}
+
+func TestNewFromMultiError(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	t.Run("multierror", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			merr := &fakeMultiError{Errors: []error{
+				errors.New("name is required"),
+				errors.New("email is invalid"),
+				errors.New("age must be positive"),
+			}}
+			AbortWithError(c, 500, NewFromMultiError(422, merr))
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 422)
+		assert.Equal(t, "name is required; email is invalid; age must be positive", readLog(t))
+		body := parseBody(t, res)
+		assert.Equal(t, []interface{}{"name is required", "email is invalid", "age must be positive"}, body["errors"])
+	})
+	t.Run("nested join", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			merr := fakeJoinError{errs: []error{
+				errors.New("name is required"),
+				&fakeMultiError{Errors: []error{errors.New("email is invalid"), errors.New("age must be positive")}},
+			}}
+			AbortWithError(c, 500, NewFromMultiError(422, merr))
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 422)
+		assert.Equal(t, "joined error", readLog(t))
+		body := parseBody(t, res)
+		assert.Equal(t, []interface{}{"name is required", "email is invalid", "age must be positive"}, body["errors"])
+	})
+	t.Run("wrapped multierror", func(t *testing.T) {
+		path := getTestPath()
+		router.GET(path, func(c *gin.Context) {
+			merr := &fakeMultiError{Errors: []error{errors.New("name is required"), errors.New("email is invalid")}}
+			AbortWithError(c, 500, NewFromMultiError(422, fmt.Errorf("validate user: %w", merr)))
+		})
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 422)
+		assert.Equal(t, "validate user: name is required; email is invalid", readLog(t))
+		body := parseBody(t, res)
+		assert.Equal(t, []interface{}{"name is required", "email is invalid"}, body["errors"])
+	})
+}
+
+func TestMaxBodyErrors(t *testing.T) {
+	merr := &fakeMultiError{}
+	for i := 0; i < 100; i++ {
+		merr.Errors = append(merr.Errors, fmt.Errorf("row %d is invalid", i))
+	}
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		LoggingFunc:   func(code int, err error) {},
+		MaxBodyErrors: 10,
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithError(c, 500, NewFromMultiError(422, merr))
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 422, res.Code)
+	var body struct {
+		Errors []string `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+	assert.Len(t, body.Errors, 11)
+	assert.Equal(t, "row 0 is invalid", body.Errors[0])
+	assert.Equal(t, "row 9 is invalid", body.Errors[9])
+	assert.Equal(t, "+90 more", body.Errors[10])
+}
@@ -0,0 +1,46 @@
+package gerror
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// ItemResult is the result of an item of a batch request.
+type ItemResult struct {
+	ID   string
+	Code int
+	Err  error
+}
+
+type itemStatus struct {
+	ID      string `json:"id"`
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// multiStatus is the meta of the errors pushed by AbortWithMultiStatus.
+type multiStatus struct {
+	results []ItemResult
+}
+
+type multiStatusResponse struct {
+	Results []itemStatus `json:"results"`
+}
+
+// AbortWithMultiStatus aborts a batch request whose items partly failed. The
+// middleware writes a 207 Multi-Status with the code and message of each item.
+func AbortWithMultiStatus(c *gin.Context, results []ItemResult) {
+	AbortWithMeta(c, 207, nil, "", multiStatus{results: results})
+}
+
+func newMultiStatusResponse(results []ItemResult) multiStatusResponse {
+	body := multiStatusResponse{Results: make([]itemStatus, len(results))}
+	for i, result := range results {
+		body.Results[i] = itemStatus{ID: result.ID, Code: result.Code}
+		if gError, ok := result.Err.(GError); ok {
+			body.Results[i].Message = gError.Hint
+		} else if result.Err != nil {
+			body.Results[i].Message = result.Err.Error()
+		}
+	}
+	return body
+}
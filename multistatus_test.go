@@ -0,0 +1,44 @@
+package gerror
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAbortWithMultiStatus(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.POST(path, func(c *gin.Context) {
+		AbortWithMultiStatus(c, []ItemResult{
+			{ID: "1", Code: 200},
+			{ID: "2", Code: 404, Err: NewHint(404, "item not found")},
+			{ID: "3", Code: 404, Err: errors.New("no such item")},
+		})
+	})
+	res := performRequest(router, "POST", path)
+	assert.Equal(t, res.Code, 207)
+	assert.Equal(t, "", readLog(t))
+	body := parseBody(t, res)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": "1", "code": float64(200)},
+		map[string]interface{}{"id": "2", "code": float64(404), "message": "item not found"},
+		map[string]interface{}{"id": "3", "code": float64(404), "message": "no such item"},
+	}, body["results"])
+}
+
+func TestAbortAfterMultiStatus(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.POST(path, func(c *gin.Context) {
+		AbortWithMultiStatus(c, []ItemResult{{ID: "1", Code: 200}})
+		AbortWithErrorAndHint(c, 500, errors.New("commit failed"), "Internal error")
+	})
+	res := performRequest(router, "POST", path)
+	assert.Equal(t, 500, res.Code)
+	assert.Equal(t, `{"message":"Internal error"}`, res.Body.String())
+	assert.Equal(t, "commit failed", readLog(t))
+}
@@ -0,0 +1,31 @@
+package gerror
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const middlewareKey = "github.com/dcalsky/gerror/middleware"
+
+var noMiddlewareWarning sync.Once
+
+// WithoutMiddleware marks the context as handled without Middleware on
+// purpose, e.g. by a unit test calling a handler directly, so the abort
+// helpers don't warn about the missing middleware.
+func WithoutMiddleware(c *gin.Context) {
+	c.Set(middlewareKey, false)
+}
+
+// warnWithoutMiddleware logs once if an abort helper is used in a request not
+// handled by Middleware or MiddlewareCollect, which would answer with an empty
+// 200 instead of the error.
+func warnWithoutMiddleware(c *gin.Context) {
+	if _, ok := c.Get(middlewareKey); ok {
+		return
+	}
+	noMiddlewareWarning.Do(func() {
+		logrus.Warnln("gerror: an abort helper was used without gerror.Middleware, the error response is not written")
+	})
+}
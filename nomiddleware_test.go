@@ -0,0 +1,50 @@
+package gerror
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestWarnWithoutMiddleware(t *testing.T) {
+	noMiddlewareWarning = sync.Once{}
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{LoggingFunc: func(code int, err error) {}}))
+	router.GET("/handled", func(c *gin.Context) {
+		AbortWithHint(c, 400, "Invalid input")
+	})
+	performRequest(router, "GET", "/handled")
+	assert.Empty(t, hook.AllEntries())
+
+	router = gin.New()
+	router.GET("/unhandled", func(c *gin.Context) {
+		AbortWithHint(c, 400, "Invalid input")
+	})
+	for i := 0; i < 3; i++ {
+		res := performRequest(router, "GET", "/unhandled")
+		assert.Equal(t, 200, res.Code)
+	}
+	assert.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, "gerror: an abort helper was used without gerror.Middleware, the error response is not written", hook.LastEntry().Message)
+	assert.Equal(t, "gerror: an abort helper was used without gerror.Middleware, the error response is not written", readLog(t))
+}
+
+func TestWithoutMiddleware(t *testing.T) {
+	noMiddlewareWarning = sync.Once{}
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	router := gin.New()
+	router.GET("/unhandled", func(c *gin.Context) {
+		WithoutMiddleware(c)
+		AbortWithHint(c, 400, "Invalid input")
+	})
+	res := performRequest(router, "GET", "/unhandled")
+	assert.Equal(t, 200, res.Code)
+	assert.Empty(t, hook.AllEntries())
+}
@@ -0,0 +1,30 @@
+package gerror
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NoRouteHandler returns a handler for gin.Engine.NoRoute writing a 404 error
+// like Middleware, for routers where the middleware is only used by groups.
+func NoRouteHandler(option MiddlewareOption) gin.HandlerFunc {
+	return statusHandler(option, http.StatusNotFound)
+}
+
+// NoMethodHandler returns a handler for gin.Engine.NoMethod writing a 405
+// error like Middleware. gin only calls it with HandleMethodNotAllowed set.
+func NoMethodHandler(option MiddlewareOption) gin.HandlerFunc {
+	return statusHandler(option, http.StatusMethodNotAllowed)
+}
+
+func statusHandler(option MiddlewareOption, code int) gin.HandlerFunc {
+	middleware := Middleware(option)
+	return func(c *gin.Context) {
+		c.Set(middlewareKey, true)
+		AbortWithHint(c, code, http.StatusText(code))
+		middleware(c)
+		// The error is handled, a global middleware must not handle it again.
+		SkipErrorHandling(c)
+	}
+}
@@ -0,0 +1,47 @@
+package gerror
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNoRouteHandler(t *testing.T) {
+	option := MiddlewareOption{IncludeCode: true}
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(NoRouteHandler(option))
+	router.NoMethod(NoMethodHandler(option))
+	api := router.Group("/api", Middleware(option))
+	api.GET("/users", func(c *gin.Context) {
+		c.String(200, "users")
+	})
+
+	res := performRequest(router, "GET", "/api/unknown")
+	assert.Equal(t, 404, res.Code)
+	assert.Equal(t, "application/json; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Equal(t, `{"code":404,"message":"Not Found"}`, res.Body.String())
+	readLog(t)
+
+	res = performRequest(router, "POST", "/api/users")
+	assert.Equal(t, 405, res.Code)
+	assert.Equal(t, `{"code":405,"message":"Method Not Allowed"}`, res.Body.String())
+	readLog(t)
+}
+
+func TestNoRouteHandlerWithGlobalMiddleware(t *testing.T) {
+	var logged []int
+	option := MiddlewareOption{
+		LoggingFunc: func(code int, err error) {
+			logged = append(logged, code)
+		},
+	}
+	router := gin.New()
+	router.Use(Middleware(option))
+	router.NoRoute(NoRouteHandler(option))
+
+	res := performRequest(router, "GET", "/unknown")
+	assert.Equal(t, 404, res.Code)
+	assert.Equal(t, `{"message":"Not Found"}`, res.Body.String())
+	assert.Equal(t, []int{404}, logged)
+}
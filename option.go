@@ -0,0 +1,59 @@
+package gerror
+
+import (
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// With returns a copy of the option with the non-zero fields of overrides
+// applied, so route groups can derive their own option from a base one.
+// A zero value like false can't override a field.
+func (option MiddlewareOption) With(overrides MiddlewareOption) MiddlewareOption {
+	merged := reflect.ValueOf(&option).Elem()
+	values := reflect.ValueOf(overrides)
+	for i := 0; i < values.NumField(); i++ {
+		if field := values.Field(i); !field.IsZero() {
+			merged.Field(i).Set(field)
+		}
+	}
+	return option
+}
+
+// withDefaults returns a copy of the option with the defaults applied. The
+// option may be shared by several middlewares, so neither it nor its maps are
+// modified.
+func (option MiddlewareOption) withDefaults() MiddlewareOption {
+	if option.Now == nil {
+		option.Now = time.Now
+	}
+	if option.ErrorPageFunc == nil {
+		option.ErrorPageFunc = defaultErrorPage
+	}
+	if option.IDGenerator == nil {
+		option.IDGenerator = newUUID
+	}
+	if option.RequestIDHeader == "" {
+		option.RequestIDHeader = "X-Request-ID"
+	}
+	if option.TimestampFormat == "" {
+		option.TimestampFormat = time.RFC3339
+	}
+	if option.PanicCodeFunc == nil {
+		option.PanicCodeFunc = defaultPanicCode
+	}
+	if option.ErrorTypeCodes == nil {
+		option.ErrorTypeCodes = map[gin.ErrorType]int{
+			gin.ErrorTypeBind: 400,
+		}
+	}
+	if option.IncludeInstance && option.InstanceID == "" {
+		option.InstanceID, _ = os.Hostname()
+	}
+	if option.LogRateLimit > 0 && option.LogRateWindow <= 0 {
+		option.LogRateWindow = time.Minute
+	}
+	return option
+}
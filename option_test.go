@@ -0,0 +1,75 @@
+package gerror
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestOptionWith(t *testing.T) {
+	var logged []int
+	base := MiddlewareOption{
+		LoggingFunc: func(code int, err error) {
+			logged = append(logged, code)
+		},
+		IncludeCode:   true,
+		CodeFieldName: "code",
+	}
+	merged := base.With(MiddlewareOption{
+		CodeFieldName: "status",
+		NoStoreErrors: true,
+	})
+	assert.Equal(t, "code", base.CodeFieldName)
+	assert.False(t, base.NoStoreErrors)
+	assert.Equal(t, "status", merged.CodeFieldName)
+	assert.True(t, merged.NoStoreErrors)
+	assert.True(t, merged.IncludeCode)
+	assert.NotNil(t, merged.LoggingFunc)
+	assert.Nil(t, merged.ResponseBodyFunc)
+
+	router := gin.New()
+	api := router.Group("/api", Middleware(base))
+	api.GET("/error", func(c *gin.Context) {
+		AbortWithHint(c, 400, "bad input")
+	})
+	admin := router.Group("/admin", Middleware(merged))
+	admin.GET("/error", func(c *gin.Context) {
+		AbortWithHint(c, 400, "bad input")
+	})
+
+	res := performRequest(router, "GET", "/api/error")
+	assert.Equal(t, `{"code":400,"message":"bad input"}`, res.Body.String())
+	assert.Empty(t, res.Header().Get("Cache-Control"))
+	res = performRequest(router, "GET", "/admin/error")
+	assert.Equal(t, `{"status":400,"message":"bad input"}`, res.Body.String())
+	assert.Equal(t, "no-store", res.Header().Get("Cache-Control"))
+	assert.Equal(t, []int{400, 400}, logged)
+}
+
+func TestOptionSharedConcurrently(t *testing.T) {
+	option := MiddlewareOption{
+		LoggingFunc:  func(code int, err error) {},
+		LogRateLimit: 10,
+		QuietCodes:   []int{404},
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			router := gin.New()
+			router.Use(Middleware(option), MiddlewareCollect(option))
+			router.GET("/error", func(c *gin.Context) {
+				AbortWithHint(c, 404, "not found")
+			})
+			res := performRequest(router, "GET", "/error")
+			assert.Equal(t, `{"message":"not found"}`, res.Body.String())
+		}()
+	}
+	wg.Wait()
+	assert.Nil(t, option.Now)
+	assert.Nil(t, option.ErrorPageFunc)
+	assert.Nil(t, option.ErrorTypeCodes)
+	assert.Zero(t, option.LogRateWindow)
+}
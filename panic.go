@@ -0,0 +1,66 @@
+package gerror
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+func defaultPanicCode(recovered interface{}) int {
+	return 500
+}
+
+// nextRecovering runs the handlers and turns a panic into an error aborted
+// with the code returned by codeFunc, with the stack trace of the panic.
+func nextRecovering(c *gin.Context, codeFunc func(recovered interface{}) int) {
+	defer recoverPanic(c, codeFunc)
+	c.Next()
+}
+
+// Recover wraps a single handler, e.g. a third-party one, so its panics
+// become 500 errors even without RecoverPanics. With CaptureStack, the stack
+// of the panic is filtered like the stacks of the abort helpers.
+func Recover(fn gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer recoverPanic(c, defaultPanicCode)
+		fn(c)
+	}
+}
+
+// SafeHandler is the same as Recover, which it predates.
+//
+// Deprecated: Use Recover.
+func SafeHandler(handler gin.HandlerFunc) gin.HandlerFunc {
+	return Recover(handler)
+}
+
+// recoverPanic must be deferred. http.ErrAbortHandler is not recovered, as it
+// is used to abort the response on purpose.
+func recoverPanic(c *gin.Context, codeFunc func(recovered interface{}) int) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	if recovered == http.ErrAbortHandler {
+		panic(recovered)
+	}
+	var err error
+	if e, ok := recovered.(error); ok {
+		err = fmt.Errorf("panic: %w", e)
+	} else {
+		err = fmt.Errorf("panic: %v", recovered)
+	}
+	code := codeFunc(recovered)
+	gError := New(code, err, "").(GError)
+	if c.GetBool(captureCallerKey) {
+		gError.Caller = panicCaller()
+	}
+	if options, ok := c.Get(captureStackKey); ok {
+		gError.Stack = stackTrace(options.(stackOptions))
+	} else {
+		gError.Stack = string(debug.Stack())
+	}
+	AbortWithError(c, code, gError)
+}
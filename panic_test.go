@@ -0,0 +1,147 @@
+package gerror
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type authError struct {
+	user string
+}
+
+func (e authError) Error() string {
+	return "unauthenticated user " + e.user
+}
+
+func TestRecoverPanics(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		RecoverPanics: true,
+		PanicCodeFunc: func(recovered interface{}) int {
+			if _, ok := recovered.(authError); ok {
+				return 401
+			}
+			return 500
+		},
+	}))
+	authPath := getTestPath()
+	router.GET(authPath, func(c *gin.Context) {
+		panic(authError{user: "anonymous"})
+	})
+	valuePath := getTestPath()
+	router.GET(valuePath, func(c *gin.Context) {
+		panic("nil map")
+	})
+
+	res := performRequest(router, "GET", authPath)
+	assert.Equal(t, 401, res.Code)
+	assert.Equal(t, "panic: unauthenticated user anonymous", readLog(t))
+	res = performRequest(router, "GET", valuePath)
+	assert.Equal(t, 500, res.Code)
+	assert.Equal(t, "panic: nil map", readLog(t))
+}
+
+func TestRecoverPanicsDefaultCode(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{RecoverPanics: true}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		panic(authError{user: "anonymous"})
+	})
+	abortPath := getTestPath()
+	router.GET(abortPath, func(c *gin.Context) {
+		panic(http.ErrAbortHandler)
+	})
+
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 500, res.Code)
+	assert.Equal(t, "panic: unauthenticated user anonymous", readLog(t))
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		performRequest(router, "GET", abortPath)
+	})
+}
+
+func panickingHandler(c *gin.Context) {
+	var users map[string]int
+	users["gopher"]++
+}
+
+func TestRecoverPanicsStack(t *testing.T) {
+	var stack string
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		RecoverPanics: true,
+		IncludeCode:   true,
+		LoggingFunc: func(code int, err error) {
+			var gErr GError
+			if errors.As(err, &gErr) {
+				stack = gErr.Stack
+			}
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, panickingHandler)
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 500, res.Code)
+	assert.Contains(t, stack, "gerror.panickingHandler")
+	assert.NotContains(t, res.Body.String(), "panickingHandler")
+}
+
+func TestSafeHandler(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		IncludeCode:  true,
+		DefaultHints: map[int]string{500: "Internal error"},
+	}))
+	path := getTestPath()
+	router.GET(path, SafeHandler(panickingHandler))
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 500, res.Code)
+	assert.Equal(t, `{"code":500,"message":"Internal error"}`, res.Body.String())
+	assert.Equal(t, "panic: assignment to entry in nil map", readLog(t))
+}
+
+func TestRecover(t *testing.T) {
+	var gErr GError
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		IncludeCode:  true,
+		DefaultHints: map[int]string{500: "Internal error"},
+		AfterResponseFunc: func(c *gin.Context, e GError) {
+			gErr = e
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, Recover(panickingHandler))
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 500, res.Code)
+	assert.Equal(t, `{"code":500,"message":"Internal error"}`, res.Body.String())
+	assert.Equal(t, "panic: assignment to entry in nil map", readLog(t))
+	assert.Equal(t, 500, gErr.Code)
+	assert.EqualError(t, gErr, "panic: assignment to entry in nil map")
+	assert.Contains(t, gErr.StackTrace(), "gerror.panickingHandler")
+}
+
+func TestRecoverCaptureStack(t *testing.T) {
+	var stack string
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		CaptureStack:      true,
+		StackMaxFrames:    1,
+		StackSkipPackages: []string{"runtime"},
+		AfterResponseFunc: func(c *gin.Context, gErr GError) {
+			stack = gErr.StackTrace()
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, Recover(panickingHandler))
+	res := performRequest(router, "GET", path)
+	readLog(t)
+	assert.Equal(t, 500, res.Code)
+	assert.True(t, strings.HasPrefix(stack, "github.com/dcalsky/gerror.panickingHandler\n\t"), stack)
+	assert.Equal(t, 2, strings.Count(stack, "\n"))
+}
@@ -0,0 +1,126 @@
+package gerror
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Problem is the RFC 7807 problem document written when
+// MiddlewareOption.ProblemJSON is set.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// InvalidParams lists the field errors of the error in the
+	// "invalid-params" extension.
+	InvalidParams []InvalidParam `json:"-"`
+	// Extensions are additional members at the top level of the document.
+	// They can't overwrite the members above.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// InvalidParam is a member of the "invalid-params" extension of a problem
+// document.
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	fields := []jsonField{
+		{"type", p.Type},
+		{"title", p.Title},
+		{"status", p.Status},
+	}
+	if p.Detail != "" {
+		fields = append(fields, jsonField{"detail", p.Detail})
+	}
+	if p.Instance != "" {
+		fields = append(fields, jsonField{"instance", p.Instance})
+	}
+	if len(p.InvalidParams) > 0 {
+		fields = append(fields, jsonField{"invalid-params", p.InvalidParams})
+	}
+	keys := make([]string, 0, len(p.Extensions))
+	for key := range p.Extensions {
+		switch key {
+		case "type", "title", "status", "detail", "instance", "invalid-params":
+		default:
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fields = append(fields, jsonField{key, p.Extensions[key]})
+	}
+	return marshalFields(fields)
+}
+
+// WithMeta returns a copy of the error with an extension member added to its
+// problem document.
+func (g GError) WithMeta(key string, value interface{}) GError {
+	meta := make(map[string]interface{}, len(g.Meta)+1)
+	for k, v := range g.Meta {
+		meta[k] = v
+	}
+	meta[key] = value
+	g.Meta = meta
+	return g
+}
+
+// WithReason returns a copy of the error with a stable, machine-readable
+// reason like "insufficient_balance", which ProblemTypeFunc can map to the
+// type URI of the problem document.
+func (g GError) WithReason(reason string) GError {
+	g.Reason = reason
+	return g
+}
+
+func newProblem(c *gin.Context, gError GError, message string, typeFunc func(code int, reason string) string) Problem {
+	problemType := "about:blank"
+	if typeFunc != nil {
+		if uri := typeFunc(gError.Code, gError.Reason); uri != "" {
+			problemType = uri
+		}
+	}
+	var invalidParams []InvalidParam
+	for _, field := range gError.Fields {
+		reason := field.Message
+		if reason == "" {
+			reason = field.Code
+		}
+		invalidParams = append(invalidParams, InvalidParam{Name: field.Field, Reason: reason})
+	}
+	return Problem{
+		Type:          problemType,
+		Title:         http.StatusText(gError.Code),
+		Status:        gError.Code,
+		Detail:        message,
+		Instance:      c.Request.URL.Path,
+		InvalidParams: invalidParams,
+		Extensions:    gError.Meta,
+	}
+}
+
+// problemWithStatus sets the status of a problem document body, e.g. one given
+// to AbortWithBody, to the status code of the response, as required by RFC
+// 7807. Other bodies are returned as is.
+func problemWithStatus(body interface{}, code int) interface{} {
+	switch problem := body.(type) {
+	case Problem:
+		problem.Status = code
+		return problem
+	case *Problem:
+		if problem == nil {
+			return body
+		}
+		copied := *problem
+		copied.Status = code
+		return copied
+	}
+	return body
+}
@@ -0,0 +1,114 @@
+package gerror
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestProblemJSON(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{ProblemJSON: true}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		err := NewHint(403, "Your current balance is 30, but that costs 50.").(GError).
+			WithMeta("balance", 30).
+			WithMeta("status", 200).
+			WithMeta("title", "overwritten")
+		AbortWithError(c, 500, err)
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, res.Code, 403)
+	assert.Equal(t, "application/problem+json", res.Header().Get("Content-Type"))
+	assert.Equal(t, `{"type":"about:blank","title":"Forbidden","status":403,"detail":"Your current balance is 30, but that costs 50.","instance":"`+path+`","balance":30}`, res.Body.String())
+}
+
+func TestWithMeta(t *testing.T) {
+	origin := NewHint(400, "bad input").(GError).WithMeta("field", "name")
+	copied := origin.WithMeta("reason", "required")
+	assert.Equal(t, map[string]interface{}{"field": "name"}, origin.Meta)
+	assert.Equal(t, map[string]interface{}{"field": "name", "reason": "required"}, copied.Meta)
+}
+
+func TestProblemTypeFunc(t *testing.T) {
+	types := map[string]string{
+		"insufficient_balance": "https://example.com/probs/out-of-credit",
+	}
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		ProblemJSON: true,
+		ProblemTypeFunc: func(code int, reason string) string {
+			return types[reason]
+		},
+	}))
+	knownPath := getTestPath()
+	router.GET(knownPath, func(c *gin.Context) {
+		AbortWithError(c, 500, NewHint(403, "Your current balance is 30, but that costs 50.").(GError).WithReason("insufficient_balance"))
+	})
+	unknownPath := getTestPath()
+	router.GET(unknownPath, func(c *gin.Context) {
+		AbortWithError(c, 500, NewHint(404, "Account not found").(GError).WithReason("account_not_found"))
+	})
+
+	res := performRequest(router, "GET", knownPath)
+	assert.Equal(t, 403, res.Code)
+	assert.Equal(t, `{"type":"https://example.com/probs/out-of-credit","title":"Forbidden","status":403,"detail":"Your current balance is 30, but that costs 50.","instance":"`+knownPath+`"}`, res.Body.String())
+	readLog(t)
+	res = performRequest(router, "GET", unknownPath)
+	assert.Equal(t, 404, res.Code)
+	assert.Equal(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"Account not found","instance":"`+unknownPath+`"}`, res.Body.String())
+	readLog(t)
+}
+
+func TestProblemInvalidParams(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{ProblemJSON: true}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		err := NewHint(422, "Your request parameters didn't validate.").(GError).
+			WithFieldError("email", "required", "").
+			WithFieldError("age", "min", "must be a positive integer").
+			WithMeta("invalid-params", "overwritten")
+		AbortWithError(c, 422, err)
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 422, res.Code)
+	assert.Equal(t, `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"Your request parameters didn't validate.","instance":"`+path+`",`+
+		`"invalid-params":[{"name":"email","reason":"required"},{"name":"age","reason":"must be a positive integer"}]}`, res.Body.String())
+	readLog(t)
+}
+
+func TestProblemStatusMatchesResponse(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		ProblemJSON: true,
+		StatusRewriteFunc: func(code int) int {
+			if code == 418 {
+				return 400
+			}
+			return code
+		},
+	}))
+	rewrittenPath := getTestPath()
+	router.GET(rewrittenPath, func(c *gin.Context) {
+		AbortWithError(c, 418, NewHint(418, "No coffee").(GError).WithMeta("code", 41801))
+	})
+	bodyPath := getTestPath()
+	router.GET(bodyPath, func(c *gin.Context) {
+		AbortWithBody(c, 404, &Problem{Type: "about:blank", Title: "Not Found", Status: 40401})
+	})
+
+	res := performRequest(router, "GET", rewrittenPath)
+	readLog(t)
+	assert.Equal(t, 400, res.Code)
+	assert.Equal(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"No coffee","instance":"`+rewrittenPath+`","code":41801}`, res.Body.String())
+	var problem map[string]interface{}
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &problem))
+	assert.Equal(t, float64(res.Code), problem["status"])
+
+	res = performRequest(router, "GET", bodyPath)
+	readLog(t)
+	assert.Equal(t, 404, res.Code)
+	assert.Equal(t, `{"type":"about:blank","title":"Not Found","status":404}`, res.Body.String())
+}
@@ -0,0 +1,41 @@
+package gerror
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// detachedContext keeps the values of the request context, but is not
+// canceled when the request ends.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+// publish calls publishFunc in its own goroutine, so a slow message bus
+// doesn't delay the response. Its failures are only logged.
+func publish(ctx context.Context, publishFunc func(ctx context.Context, gErr GError) error, gError GError) {
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				logrus.Errorf("gerror: publishing the error panicked: %v", recovered)
+			}
+		}()
+		if err := publishFunc(detachedContext{ctx}, gError); err != nil {
+			logrus.Errorf("gerror: failed to publish the error: %v", err)
+		}
+	}()
+}
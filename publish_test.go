@@ -0,0 +1,84 @@
+package gerror
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type notifyWriter struct {
+	buf     bytes.Buffer
+	written chan struct{}
+}
+
+func (w *notifyWriter) Write(p []byte) (int, error) {
+	n, err := w.buf.Write(p)
+	w.written <- struct{}{}
+	return n, err
+}
+
+type tenantKey struct{}
+
+func TestPublishFunc(t *testing.T) {
+	published := make(chan GError, 1)
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		LoggingFunc: func(code int, err error) {},
+		PublishFunc: func(ctx context.Context, gErr GError) error {
+			if ctx.Value(tenantKey{}) != "acme" || ctx.Err() != nil {
+				return errors.New("unexpected context")
+			}
+			if gErr.Code == 503 {
+				return errors.New("broker unavailable")
+			}
+			published <- gErr
+			return nil
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 409, "User already exists")
+	})
+	failingPath := getTestPath()
+	router.GET(failingPath, func(c *gin.Context) {
+		AbortWithHint(c, 503, "Try again later")
+	})
+	request := func(path string) *httptest.ResponseRecorder {
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), tenantKey{}, "acme"))
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, "GET", path, nil)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	res := request(path)
+	assert.Equal(t, 409, res.Code)
+	select {
+	case gErr := <-published:
+		assert.Equal(t, 409, gErr.Code)
+		assert.Equal(t, "User already exists", gErr.Hint)
+	case <-time.After(time.Second):
+		t.Fatal("the error was not published")
+	}
+
+	output := &notifyWriter{written: make(chan struct{}, 1)}
+	logrus.SetOutput(output)
+	defer logrus.SetOutput(&buf)
+	res = request(failingPath)
+	assert.Equal(t, 503, res.Code)
+	assert.Equal(t, `{"message":"Try again later"}`, res.Body.String())
+	select {
+	case <-output.written:
+		assert.Equal(t, "gerror: failed to publish the error: broker unavailable", output.buf.String())
+	case <-time.After(time.Second):
+		t.Fatal("the failure was not logged")
+	}
+}
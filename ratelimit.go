@@ -0,0 +1,47 @@
+package gerror
+
+import (
+	"sync"
+	"time"
+)
+
+type logWindow struct {
+	start time.Time
+	count int
+}
+
+type logLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	lastSweep time.Time
+	windows   map[string]*logWindow
+}
+
+func newLogLimiter(limit int, window time.Duration) *logLimiter {
+	return &logLimiter{
+		limit:   limit,
+		window:  window,
+		windows: map[string]*logWindow{},
+	}
+}
+
+func (l *logLimiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) >= l.window {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &logWindow{start: now}
+		l.windows[key] = w
+	}
+	w.count++
+	return w.count <= l.limit
+}
@@ -0,0 +1,49 @@
+package gerror
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLogRateLimit(t *testing.T) {
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	var logged []string
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		LoggingFunc: func(code int, err error) {
+			logged = append(logged, err.Error())
+		},
+		Now: func() time.Time {
+			return now
+		},
+		LogRateLimit:  2,
+		LogRateWindow: time.Minute,
+	}))
+	path := getTestPath()
+	var i int
+	router.GET(path, func(c *gin.Context) {
+		i++
+		AbortWithError(c, 500, fmt.Errorf("order %d failed", i))
+	})
+	otherPath := getTestPath()
+	router.GET(otherPath, func(c *gin.Context) {
+		AbortWithError(c, 500, errors.New("other error"))
+	})
+
+	for j := 0; j < 5; j++ {
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 500)
+	}
+	assert.Equal(t, []string{"order 1 failed", "order 2 failed"}, logged)
+
+	performRequest(router, "GET", otherPath)
+	assert.Equal(t, []string{"order 1 failed", "order 2 failed", "other error"}, logged)
+
+	now = now.Add(time.Minute)
+	performRequest(router, "GET", path)
+	assert.Equal(t, []string{"order 1 failed", "order 2 failed", "other error", "order 6 failed"}, logged)
+}
@@ -0,0 +1,111 @@
+package gerror
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WithReasonPhrase returns a copy of the error whose response uses a custom
+// reason phrase in the status line, e.g. "418 Short and stout". Only HTTP/1.x
+// responses of a net/http server support it, HTTP/2 has no reason phrase and
+// the standard one is used instead.
+//
+// net/http always writes the standard reason phrase, so the response is
+// written on the hijacked connection, which is then closed: the client can't
+// reuse it for another request.
+func (g GError) WithReasonPhrase(phrase string) GError {
+	g.ReasonPhrase = phrase
+	return g
+}
+
+func reasonPhraseSupported(c *gin.Context) bool {
+	return c.Request.ProtoMajor == 1 && c.Request.Context().Value(http.ServerContextKey) != nil && !c.Writer.Written()
+}
+
+// writeWithReasonPhrase buffers the response written by write, whatever its
+// format, and writes it with the reason phrase on the hijacked connection.
+func writeWithReasonPhrase(c *gin.Context, code int, phrase string, write func()) {
+	writer := c.Writer
+	buffered := &bufferedWriter{ResponseWriter: writer, header: writer.Header().Clone(), status: code}
+	c.Writer = buffered
+	write()
+	c.Writer = writer
+	conn, rw, err := writer.Hijack()
+	if err != nil {
+		for key, values := range buffered.header {
+			writer.Header()[key] = values
+		}
+		writer.WriteHeader(buffered.status)
+		_, _ = writer.Write(buffered.body.Bytes())
+		return
+	}
+	defer conn.Close()
+	res := &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, phrase),
+		StatusCode:    code,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        buffered.header,
+		Body:          ioutil.NopCloser(&buffered.body),
+		ContentLength: int64(buffered.body.Len()),
+		Close:         true,
+	}
+	if err := res.Write(rw); err == nil {
+		_ = rw.Flush()
+	}
+}
+
+// bufferedWriter keeps the status, the header and the body instead of writing
+// them.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	header  http.Header
+	status  int
+	body    bytes.Buffer
+	written bool
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+func (w *bufferedWriter) Flush() {}
@@ -0,0 +1,76 @@
+package gerror
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithReasonPhrase(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		err := NewHint(418, "no coffee").(GError).WithReasonPhrase("Short and stout")
+		AbortWithError(c, 500, err)
+	})
+
+	t.Run("http/1.1", func(t *testing.T) {
+		server := httptest.NewServer(router)
+		defer server.Close()
+		res, err := http.Get(server.URL + path)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, "HTTP/1.1", res.Proto)
+		assert.Equal(t, "418 Short and stout", res.Status)
+		assert.Equal(t, "application/json; charset=utf-8", res.Header.Get("Content-Type"))
+		data, err := ioutil.ReadAll(res.Body)
+		assert.NoError(t, err)
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(data, &body))
+		assert.Equal(t, "no coffee", body["message"])
+	})
+	t.Run("unsupported writer", func(t *testing.T) {
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, res.Code, 418)
+		body := parseBody(t, res)
+		assert.Equal(t, "no coffee", body["message"])
+	})
+}
+
+func TestWithReasonPhraseFormats(t *testing.T) {
+	for _, test := range []struct {
+		option      MiddlewareOption
+		contentType string
+		body        string
+	}{
+		{MiddlewareOption{ProblemJSON: true}, "application/problem+json", `{"type":"about:blank","title":"I'm a teapot","status":418,"detail":"no coffee","instance":"/teapot"}`},
+		{MiddlewareOption{HALJSON: true}, "application/hal+json", `{"_links":{"self":{"href":"/teapot"}},"message":"no coffee"}`},
+		{MiddlewareOption{CSVErrors: true}, "text/csv; charset=utf-8", "error\nno coffee\n"},
+		{MiddlewareOption{XMLFaults: true}, "text/xml; charset=utf-8", `<?xml version="1.0" encoding="UTF-8"?>` + "\n<fault><code>418</code><message>no coffee</message></fault>"},
+		{MiddlewareOption{PrettyInDebug: true}, "application/json; charset=utf-8", "{\n    \"message\": \"no coffee\"\n}"},
+	} {
+		router := gin.New()
+		router.Use(Middleware(test.option))
+		router.GET("/teapot", func(c *gin.Context) {
+			AbortWithError(c, 418, NewHint(418, "no coffee").(GError).WithReasonPhrase("Short and stout"))
+		})
+		server := httptest.NewServer(router)
+		res, err := http.Get(server.URL + "/teapot")
+		if assert.NoError(t, err) {
+			data, err := ioutil.ReadAll(res.Body)
+			assert.NoError(t, err)
+			res.Body.Close()
+			assert.Equal(t, "418 Short and stout", res.Status)
+			assert.True(t, res.Close)
+			assert.Equal(t, test.contentType, res.Header.Get("Content-Type"))
+			assert.Equal(t, test.body, string(data))
+		}
+		server.Close()
+	}
+	readLog(t)
+}
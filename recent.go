@@ -0,0 +1,95 @@
+package gerror
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type recentError struct {
+	time  time.Time
+	error GError
+}
+
+type errorRing struct {
+	mu      sync.Mutex
+	entries []recentError
+	next    int
+	full    bool
+}
+
+var recentErrors = &errorRing{}
+
+func (r *errorRing) resize(capacity int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if capacity == len(r.entries) {
+		return
+	}
+	if capacity <= 0 {
+		r.entries, r.next, r.full = nil, 0, false
+		return
+	}
+	entries := r.list()
+	if len(entries) > capacity {
+		entries = entries[len(entries)-capacity:]
+	}
+	r.entries = make([]recentError, capacity)
+	copy(r.entries, entries)
+	r.next = len(entries) % capacity
+	r.full = len(entries) == capacity
+}
+
+func (r *errorRing) add(t time.Time, err GError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) == 0 {
+		return
+	}
+	r.entries[r.next] = recentError{time: t, error: err}
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *errorRing) list() []recentError {
+	if !r.full {
+		return append([]recentError(nil), r.entries[:r.next]...)
+	}
+	return append(append([]recentError(nil), r.entries[r.next:]...), r.entries[:r.next]...)
+}
+
+func (r *errorRing) snapshot() []recentError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.list()
+}
+
+// RecentErrors returns the last errors handled by a middleware with
+// RecentErrorsCapacity set, from the oldest to the newest.
+func RecentErrors() []GError {
+	entries := recentErrors.snapshot()
+	errs := make([]GError, len(entries))
+	for i, entry := range entries {
+		errs[i] = entry.error
+	}
+	return errs
+}
+
+// RecentErrorsHandler writes the recent errors with their timestamps as JSON.
+// It is meant for debugging and should not be exposed publicly.
+func RecentErrorsHandler(c *gin.Context) {
+	entries := recentErrors.snapshot()
+	body := make([]gin.H, len(entries))
+	for i, entry := range entries {
+		body[i] = gin.H{
+			"time":  entry.time,
+			"code":  entry.error.Code,
+			"hint":  entry.error.Hint,
+			"error": entry.error.Error(),
+		}
+	}
+	c.JSON(200, body)
+}
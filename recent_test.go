@@ -0,0 +1,51 @@
+package gerror
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRecentErrors(t *testing.T) {
+	defer recentErrors.resize(0)
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		RecentErrorsCapacity: 3,
+		Now: func() time.Time {
+			return now
+		},
+	}))
+	path := getTestPath()
+	var i int
+	router.GET(path, func(c *gin.Context) {
+		i++
+		AbortWithHint(c, 400+i, fmt.Sprintf("error %d", i))
+	})
+	router.GET("/debug/errors", RecentErrorsHandler)
+
+	performRequest(router, "GET", path)
+	performRequest(router, "GET", path)
+	assert.Equal(t, []GError{
+		{Code: 401, Hint: "error 1"},
+		{Code: 402, Hint: "error 2"},
+	}, RecentErrors())
+
+	performRequest(router, "GET", path)
+	performRequest(router, "GET", path)
+	assert.Equal(t, []GError{
+		{Code: 402, Hint: "error 2"},
+		{Code: 403, Hint: "error 3"},
+		{Code: 404, Hint: "error 4"},
+	}, RecentErrors())
+
+	res := performRequest(router, "GET", "/debug/errors")
+	assert.Equal(t, res.Code, 200)
+	assert.JSONEq(t, `[
+		{"time":"2021-05-01T12:00:00Z","code":402,"hint":"error 2","error":""},
+		{"time":"2021-05-01T12:00:00Z","code":403,"hint":"error 3","error":""},
+		{"time":"2021-05-01T12:00:00Z","code":404,"hint":"error 4","error":""}
+	]`, res.Body.String())
+}
@@ -0,0 +1,38 @@
+package gerror
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	renderersMu  sync.RWMutex
+	renderers    = map[string]func(c *gin.Context, code int, body interface{}){}
+	contentTypes []string
+)
+
+// RegisterRenderer registers the renderer of the error bodies for the content
+// type. The middleware negotiates the content type with the Accept header of
+// the request and falls back to JSON if no registered renderer matches.
+// Registering a content type again replaces its renderer.
+func RegisterRenderer(contentType string, fn func(c *gin.Context, code int, body interface{})) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	if _, ok := renderers[contentType]; !ok {
+		contentTypes = append(contentTypes, contentType)
+	}
+	renderers[contentType] = fn
+}
+
+// negotiatedRenderer returns the registered renderer of the content type
+// accepted by the client. JSON is preferred when the client accepts anything.
+func negotiatedRenderer(c *gin.Context) func(c *gin.Context, code int, body interface{}) {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	if len(renderers) == 0 {
+		return nil
+	}
+	offered := append([]string{gin.MIMEJSON}, contentTypes...)
+	return renderers[c.NegotiateFormat(offered...)]
+}
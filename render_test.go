@@ -0,0 +1,90 @@
+package gerror
+
+import (
+	"encoding/xml"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type xmlError struct {
+	XMLName xml.Name `xml:"error"`
+	Code    int      `xml:"code"`
+	Message string   `xml:"message"`
+}
+
+func unregisterRenderer(contentType string) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	delete(renderers, contentType)
+	for i, registered := range contentTypes {
+		if registered == contentType {
+			contentTypes = append(contentTypes[:i], contentTypes[i+1:]...)
+			break
+		}
+	}
+}
+
+func performRequestAccepting(r http.Handler, path, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Accept", accept)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegisterRenderer(t *testing.T) {
+	RegisterRenderer(gin.MIMEXML, func(c *gin.Context, code int, body interface{}) {
+		response := body.(ErrorResponse)
+		c.XML(code, xmlError{Code: code, Message: response.Message})
+	})
+	defer unregisterRenderer(gin.MIMEXML)
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithErrorAndHint(c, 404, errors.New("no rows"), "User not found")
+	})
+
+	w := performRequestAccepting(router, path, "application/xml")
+	readLog(t)
+	assert.Equal(t, 404, w.Code)
+	assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "<error><code>404</code><message>User not found</message></error>", w.Body.String())
+
+	w = performRequestAccepting(router, path, "application/json")
+	readLog(t)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"message":"User not found"}`, w.Body.String())
+
+	w = performRequestAccepting(router, path, "*/*")
+	readLog(t)
+	assert.Equal(t, `{"message":"User not found"}`, w.Body.String())
+
+	w = performRequestAccepting(router, path, "text/plain")
+	readLog(t)
+	assert.Equal(t, `{"message":"User not found"}`, w.Body.String())
+}
+
+func TestRegisterRendererReplaces(t *testing.T) {
+	RegisterRenderer("text/plain", func(c *gin.Context, code int, body interface{}) {
+		c.String(code, "first")
+	})
+	RegisterRenderer("text/plain", func(c *gin.Context, code int, body interface{}) {
+		c.String(code, body.(ErrorResponse).Message)
+	})
+	defer unregisterRenderer("text/plain")
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 400, "Invalid id")
+	})
+
+	w := performRequestAccepting(router, path, "text/plain")
+	assert.Equal(t, "Invalid id", w.Body.String())
+	assert.Equal(t, []string{"text/plain"}, contentTypes)
+}
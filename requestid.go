@@ -0,0 +1,37 @@
+package gerror
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+const (
+	requestIDKey       = "github.com/dcalsky/gerror/requestID"
+	maxRequestIDLength = 128
+)
+
+// validRequestID reports whether an ID sent by the client can be echoed and
+// logged: it must be short and made of visible ASCII characters, so it can't
+// break a header or a log line.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newUUID returns a random UUID version 4.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,138 @@
+package gerror
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGenerateRequestID(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	var next int
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		GenerateRequestID: true,
+		IDGenerator: func() string {
+			next++
+			return fmt.Sprintf("req-%d", next)
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 404, "User not found")
+	})
+
+	for _, id := range []string{"req-1", "req-2"} {
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, `{"message":"User not found","request_id":"`+id+`"}`, res.Body.String())
+		assert.Equal(t, id, res.Header().Get("X-Request-ID"))
+		readLog(t)
+		assert.Equal(t, id, hook.LastEntry().Data["request_id"])
+	}
+}
+
+func TestRequestIDHeader(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		GenerateRequestID: true,
+		RequestIDHeader:   "X-Correlation-ID",
+		IDGenerator: func() string {
+			return "generated"
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 404, "User not found")
+	})
+	okPath := getTestPath()
+	router.GET(okPath, func(c *gin.Context) {
+		c.String(200, "ok")
+	})
+
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("X-Correlation-ID", "incoming")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	readLog(t)
+	assert.Equal(t, "incoming", res.Header().Get("X-Correlation-ID"))
+	assert.Equal(t, `{"message":"User not found","request_id":"incoming"}`, res.Body.String())
+
+	res = performRequest(router, "GET", path)
+	readLog(t)
+	assert.Equal(t, "generated", res.Header().Get("X-Correlation-ID"))
+	assert.Empty(t, res.Header().Get("X-Request-ID"))
+
+	res = performRequest(router, "GET", okPath)
+	assert.Empty(t, res.Header().Get("X-Correlation-ID"))
+}
+
+func TestRequestIDHeaderWithoutGenerateRequestID(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 404, "User not found")
+	})
+
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("X-Request-ID", "incoming")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	readLog(t)
+	assert.Equal(t, "incoming", res.Header().Get("X-Request-ID"))
+	assert.Equal(t, `{"message":"User not found"}`, res.Body.String())
+	assert.NotContains(t, hook.LastEntry().Data, "request_id")
+
+	res = performRequest(router, "GET", path)
+	readLog(t)
+	assert.Empty(t, res.Header().Get("X-Request-ID"))
+}
+
+func TestInvalidIncomingRequestID(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		GenerateRequestID: true,
+		IDGenerator: func() string {
+			return "generated"
+		},
+	}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithHint(c, 404, "User not found")
+	})
+
+	for _, id := range []string{strings.Repeat("a", 129), "req 1", "req-\u00e9", "req-\x7f"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-Request-ID", id)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		readLog(t)
+		assert.Equal(t, "generated", res.Header().Get("X-Request-ID"), id)
+		assert.Equal(t, `{"message":"User not found","request_id":"generated"}`, res.Body.String(), id)
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	assert.True(t, validRequestID("9f1c2e9a-3b7d-4c1e-8f2a-6d5b4c3a2b1c"))
+	assert.True(t, validRequestID("Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1"))
+	assert.True(t, validRequestID(strings.Repeat("a", 128)))
+	assert.False(t, validRequestID(""))
+	assert.False(t, validRequestID(strings.Repeat("a", 129)))
+	assert.False(t, validRequestID("req\nforged"))
+}
+
+func TestNewUUID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first, second := newUUID(), newUUID()
+	assert.Regexp(t, pattern, first)
+	assert.Regexp(t, pattern, second)
+	assert.NotEqual(t, first, second)
+}
@@ -0,0 +1,32 @@
+package gerror
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const routeTemplateKey = "github.com/dcalsky/gerror/routeTemplate"
+
+// RoutePath returns the path to label the logs and the metrics of errors
+// with: the route template, like /users/:id, if the middleware uses
+// UseRouteTemplate, the raw path of the request otherwise.
+func RoutePath(c *gin.Context) string {
+	if c.GetBool(routeTemplateKey) {
+		if fullPath := c.FullPath(); fullPath != "" {
+			return fullPath
+		}
+	}
+	return c.Request.URL.Path
+}
+
+// matchesPath reports whether the path is one of the paths or below one of
+// them.
+func matchesPath(path string, paths []string) bool {
+	for _, p := range paths {
+		if path == p || strings.HasPrefix(path, strings.TrimSuffix(p, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
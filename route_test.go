@@ -0,0 +1,121 @@
+package gerror
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestUseRouteTemplate(t *testing.T) {
+	var out bytes.Buffer
+	var paths []string
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		UseRouteTemplate:       true,
+		LoggingFuncWithContext: LogfmtLoggingFunc(&out),
+		AfterResponseFunc: func(c *gin.Context, gErr GError) {
+			paths = append(paths, RoutePath(c))
+		},
+	}))
+	router.GET("/users/:id", func(c *gin.Context) {
+		AbortWithError(c, 500, errors.New("sql: connection refused"))
+	})
+	router.NoRoute(func(c *gin.Context) {
+		AbortWithHint(c, 404, "Not found")
+	})
+
+	performRequest(router, "GET", "/users/42")
+	performRequest(router, "GET", "/users/42/posts")
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, "/users/:id", parseLogfmt(t, lines[0])["path"])
+		assert.Equal(t, "/users/42/posts", parseLogfmt(t, lines[1])["path"])
+	}
+	assert.Equal(t, []string{"/users/:id", "/users/42/posts"}, paths)
+}
+
+func TestUseRouteTemplateMetricsAndLogs(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	counter := map[string]int{}
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		UseRouteTemplate: true,
+		CountErrorFunc: func(ctx context.Context, code int, method, route string) {
+			counter[route]++
+		},
+	}))
+	router.GET("/users/:id", func(c *gin.Context) {
+		AbortWithError(c, 500, errors.New("sql: connection refused"))
+	})
+
+	performRequest(router, "GET", "/users/42")
+	performRequest(router, "GET", "/users/43")
+	assert.Equal(t, "sql: connection refusedsql: connection refused", readLog(t))
+	assert.Equal(t, map[string]int{"/users/:id": 2}, counter)
+	assert.Equal(t, "/users/:id", hook.LastEntry().Data["route"])
+}
+
+func TestUseRouteTemplateDisabled(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	var paths, routes []string
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		CountErrorFunc: func(ctx context.Context, code int, method, route string) {
+			routes = append(routes, route)
+		},
+		AfterResponseFunc: func(c *gin.Context, gErr GError) {
+			paths = append(paths, RoutePath(c))
+		},
+	}))
+	router.GET("/users/:id", func(c *gin.Context) {
+		AbortWithHint(c, 404, "User not found")
+	})
+
+	performRequest(router, "GET", "/users/42")
+	readLog(t)
+	assert.Equal(t, []string{"/users/42"}, paths)
+	assert.Equal(t, []string{"/users/42"}, routes)
+	assert.NotContains(t, hook.LastEntry().Data, "route")
+}
+
+func TestSkipLogPaths(t *testing.T) {
+	var logged []string
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		SkipLogPaths: []string{"/healthz", "/metrics/"},
+		LoggingFunc: func(code int, err error) {
+			logged = append(logged, err.Error())
+		},
+	}))
+	paths := []string{"/healthz", "/healthz/ready", "/healthzz", "/metrics/go", "/api/users"}
+	for _, path := range paths {
+		path := path
+		router.GET(path, func(c *gin.Context) {
+			AbortWithError(c, 503, errors.New(path))
+		})
+	}
+
+	for _, path := range paths {
+		res := performRequest(router, "GET", path)
+		assert.Equal(t, 503, res.Code)
+	}
+	assert.Equal(t, []string{"/healthzz", "/api/users"}, logged)
+}
+
+func TestMatchesPath(t *testing.T) {
+	paths := []string{"/healthz", "/internal/"}
+	assert.True(t, matchesPath("/healthz", paths))
+	assert.True(t, matchesPath("/healthz/live", paths))
+	assert.True(t, matchesPath("/internal/metrics", paths))
+	assert.False(t, matchesPath("/internal", paths))
+	assert.False(t, matchesPath("/healthzz", paths))
+	assert.False(t, matchesPath("/api", paths))
+	assert.False(t, matchesPath("/api", nil))
+}
@@ -0,0 +1,27 @@
+//go:build go1.21
+
+package gerror
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SlogLoggingFunc returns a LoggingFuncWithContext logging each error to
+// logger with the code, method, path and error attributes. 5xx are logged at
+// error level, the other codes at warning level.
+func SlogLoggingFunc(logger *slog.Logger) func(c *gin.Context, code int, err error) {
+	return func(c *gin.Context, code int, err error) {
+		level := slog.LevelWarn
+		if code >= 500 {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(c.Request.Context(), level, err.Error(),
+			slog.Int("code", code),
+			slog.String("method", c.Request.Method),
+			slog.String("path", RoutePath(c)),
+			slog.String("error", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,65 @@
+//go:build go1.21
+
+package gerror
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+type recordingHandler struct {
+	records []slog.Record
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *recordingHandler) WithGroup(string) slog.Handler { return h }
+
+func recordAttrs(r slog.Record) map[string]interface{} {
+	attrs := map[string]interface{}{}
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.Any()
+		return true
+	})
+	return attrs
+}
+
+func TestSlogLoggingFunc(t *testing.T) {
+	handler := &recordingHandler{}
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{LoggingFuncWithContext: SlogLoggingFunc(slog.New(handler))}))
+	router.GET("/users/:id", func(c *gin.Context) {
+		AbortWithError(c, 500, errors.New("sql: connection refused"))
+	})
+	router.POST("/users", func(c *gin.Context) {
+		AbortWithError(c, 400, errors.New("invalid"))
+	})
+
+	performRequest(router, "GET", "/users/42")
+	performRequest(router, "POST", "/users")
+	if !assert.Len(t, handler.records, 2) {
+		return
+	}
+	assert.Equal(t, slog.LevelError, handler.records[0].Level)
+	assert.Equal(t, "sql: connection refused", handler.records[0].Message)
+	assert.Equal(t, map[string]interface{}{
+		"code":   int64(500),
+		"method": "GET",
+		"path":   "/users/42",
+		"error":  "sql: connection refused",
+	}, recordAttrs(handler.records[0]))
+	assert.Equal(t, slog.LevelWarn, handler.records[1].Level)
+	assert.Equal(t, int64(400), recordAttrs(handler.records[1])["code"])
+	assert.Equal(t, "POST", recordAttrs(handler.records[1])["method"])
+}
@@ -0,0 +1,33 @@
+package gerror
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+const envelopeKey = "github.com/dcalsky/gerror/envelope"
+
+type envelope struct {
+	fields      EnvelopeFields
+	includeCode bool
+}
+
+// Success writes data wrapped in the envelope of the middleware, like
+// {"success":true,"data":...}, so success and error responses look alike.
+// The code is included under the same key as in error bodies when IncludeCode
+// is set.
+func Success(c *gin.Context, code int, data interface{}) {
+	value, _ := c.Get(envelopeKey)
+	env, _ := value.(envelope)
+	keys := env.fields.withDefaults()
+	var fields []jsonField
+	if env.includeCode {
+		fields = append(fields, jsonField{keys.CodeKey, code})
+	}
+	fields = append(fields, jsonField{keys.SuccessKey, true}, jsonField{keys.DataKey, data})
+	body, err := marshalFields(fields)
+	if err != nil {
+		AbortWithError(c, 500, err)
+		return
+	}
+	c.Data(code, "application/json; charset=utf-8", body)
+}
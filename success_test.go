@@ -0,0 +1,39 @@
+package gerror
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSuccess(t *testing.T) {
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	router := gin.New()
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		Success(c, 200, user{ID: 1, Name: "gopher"})
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "application/json; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Equal(t, `{"success":true,"data":{"id":1,"name":"gopher"}}`, res.Body.String())
+
+	router = gin.New()
+	router.Use(Middleware(MiddlewareOption{
+		IncludeCode: true,
+		EnvelopeFields: EnvelopeFields{
+			CodeKey:    "status",
+			SuccessKey: "ok",
+			DataKey:    "result",
+		},
+	}))
+	router.GET(path, func(c *gin.Context) {
+		Success(c, 201, []string{"a", "b"})
+	})
+	res = performRequest(router, "GET", path)
+	assert.Equal(t, 201, res.Code)
+	assert.Equal(t, `{"status":201,"ok":true,"result":["a","b"]}`, res.Body.String())
+}
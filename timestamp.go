@@ -0,0 +1,19 @@
+package gerror
+
+import (
+	"strconv"
+	"time"
+)
+
+// TimestampEpochMillis is the TimestampFormat writing the timestamp as the
+// number of milliseconds since the Unix epoch.
+const TimestampEpochMillis = "epoch_millis"
+
+// formatTimestamp formats t with the layout and reports whether the result is
+// a number.
+func formatTimestamp(t time.Time, layout string) (string, bool) {
+	if layout == TimestampEpochMillis {
+		return strconv.FormatInt(t.UnixMilli(), 10), true
+	}
+	return t.Format(layout), false
+}
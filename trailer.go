@@ -0,0 +1,31 @@
+package gerror
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorTrailer is the trailer set by AbortWithTrailer.
+const ErrorTrailer = "X-Error"
+
+// AbortWithTrailer signals an error found while streaming the response body,
+// after the status has been sent. The hint of the error, or the status text of
+// its code, is sent in the X-Error trailer and the error is only logged, with
+// 500 unless it is a GError.
+func AbortWithTrailer(c *gin.Context, err error) {
+	gError, ok := err.(GError)
+	if !ok {
+		gError = New(500, err, "").(GError)
+	}
+	message := gError.Hint
+	if message == "" {
+		message = http.StatusText(gError.Code)
+	}
+	if !c.Writer.Written() {
+		c.Header("Trailer", ErrorTrailer)
+	}
+	c.Header(http.TrailerPrefix+ErrorTrailer, message)
+	c.Set(logOnlyKey, true)
+	AbortWithError(c, gError.Code, gError)
+}
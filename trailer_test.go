@@ -0,0 +1,38 @@
+package gerror
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAbortWithTrailer(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		c.Header("Content-Type", "text/plain")
+		_, _ = c.Writer.WriteString("row 1\n")
+		c.Writer.Flush()
+		AbortWithTrailer(c, errors.New("upstream closed"))
+	})
+	hintPath := getTestPath()
+	router.GET(hintPath, func(c *gin.Context) {
+		AbortWithTrailer(c, New(503, errors.New("export canceled"), "export interrupted"))
+		_, _ = c.Writer.WriteString("row 1\n")
+	})
+
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "row 1\n", res.Body.String())
+	assert.Equal(t, "Internal Server Error", res.Result().Trailer.Get(ErrorTrailer))
+	assert.Equal(t, "upstream closed", readLog(t))
+
+	res = performRequest(router, "GET", hintPath)
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "row 1\n", res.Body.String())
+	assert.Equal(t, ErrorTrailer, res.Header().Get("Trailer"))
+	assert.Equal(t, "export interrupted", res.Result().Trailer.Get(ErrorTrailer))
+	assert.Equal(t, "export canceled", readLog(t))
+}
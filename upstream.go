@@ -0,0 +1,28 @@
+package gerror
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const upstreamBodyLimit = 1024
+
+// FromHTTPResponse builds an error from the response of an upstream call with
+// its status code. A text body, read up to 1KB, becomes the hint. The body is
+// closed.
+func FromHTTPResponse(resp *http.Response) error {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, upstreamBodyLimit))
+	if err != nil {
+		return New(resp.StatusCode, fmt.Errorf("upstream responded %s: %w", resp.Status, err), "")
+	}
+	hint := ""
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); strings.HasPrefix(mediaType, "text/") {
+		hint = strings.TrimSpace(string(body))
+	}
+	return New(resp.StatusCode, fmt.Errorf("upstream responded %s", resp.Status), hint)
+}
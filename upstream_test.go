@@ -0,0 +1,43 @@
+package gerror
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFromHTTPResponse(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			http.Error(w, "user 42 not found", 404)
+		case "/large":
+			http.Error(w, strings.Repeat("a", 2048), 502)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(404)
+			_, _ = w.Write([]byte(`{"error":"not found"}`))
+		}
+	}))
+	defer upstream.Close()
+	get := func(path string) GError {
+		resp, err := http.Get(upstream.URL + path)
+		assert.NoError(t, err)
+		return FromHTTPResponse(resp).(GError)
+	}
+
+	gErr := get("/text")
+	assert.Equal(t, 404, gErr.Code)
+	assert.Equal(t, "user 42 not found", gErr.Hint)
+	assert.Equal(t, "upstream responded 404 Not Found", gErr.Error())
+
+	gErr = get("/json")
+	assert.Equal(t, 404, gErr.Code)
+	assert.Empty(t, gErr.Hint)
+
+	gErr = get("/large")
+	assert.Equal(t, 502, gErr.Code)
+	assert.Len(t, gErr.Hint, 1024)
+}
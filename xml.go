@@ -0,0 +1,23 @@
+package gerror
+
+import (
+	"encoding/xml"
+
+	"github.com/gin-gonic/gin"
+)
+
+type xmlFault struct {
+	XMLName xml.Name `xml:"fault"`
+	Code    int      `xml:"code"`
+	Message string   `xml:"message"`
+}
+
+// writeXMLFault writes the error as a fault element for legacy XML clients.
+func writeXMLFault(c *gin.Context, code int, message string) {
+	data, err := xml.Marshal(xmlFault{Code: code, Message: message})
+	if err != nil {
+		c.Status(code)
+		return
+	}
+	c.Data(code, "text/xml; charset=utf-8", append([]byte(xml.Header), data...))
+}
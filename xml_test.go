@@ -0,0 +1,31 @@
+package gerror
+
+import (
+	"encoding/xml"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestXMLFaults(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware(MiddlewareOption{XMLFaults: true}))
+	path := getTestPath()
+	router.GET(path, func(c *gin.Context) {
+		AbortWithErrorAndHint(c, 500, errors.New("timeout"), "Billing <legacy> is unavailable")
+	})
+	res := performRequest(router, "GET", path)
+	assert.Equal(t, 500, res.Code)
+	assert.Equal(t, "text/xml; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Equal(t, xml.Header+`<fault><code>500</code><message>Billing &lt;legacy&gt; is unavailable</message></fault>`, res.Body.String())
+	var fault struct {
+		XMLName xml.Name `xml:"fault"`
+		Code    int      `xml:"code"`
+		Message string   `xml:"message"`
+	}
+	assert.NoError(t, xml.Unmarshal(res.Body.Bytes(), &fault))
+	assert.Equal(t, 500, fault.Code)
+	assert.Equal(t, "Billing <legacy> is unavailable", fault.Message)
+	assert.Equal(t, "timeout", readLog(t))
+}